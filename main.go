@@ -1,6 +1,7 @@
 package main
 
 import (
+	"flag"
 	"log"
 
 	tea "github.com/charmbracelet/bubbletea"
@@ -11,6 +12,12 @@ import (
 
 func main() {
 	conf := config.NewConfig()
+	if args := flag.Args(); len(args) != 0 {
+		if err := ui.RunCommand(conf, args); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
 	client := openai.NewClient(conf)
 
 	m := ui.NewUi(conf, client)
@@ -0,0 +1,81 @@
+// Package atomicfile writes files shared between lazyreview instances without
+// leaving them truncated, and serializes their read-modify-write cycles.
+package atomicfile
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Time to wait for another process to release a lock
+const lockTimeout = 10 * time.Second
+
+const lockRetryInterval = 50 * time.Millisecond
+
+// ErrLockTimeout is returned when a lock is held by another process for too long.
+var ErrLockTimeout = errors.New("timed out waiting for the file lock")
+
+// WriteFile writes data to a temporary file in the directory of path and renames it over path,
+// so that readers see either the old or the new content. Missing directories are created.
+func WriteFile(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Lock takes an advisory exclusive lock on path, held on a separate lock file as path
+// itself is replaced by WriteFile. The returned function releases the lock.
+func Lock(path string) (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path+".lock", os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		locked, err := tryLock(f)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		if locked {
+			break
+		}
+		if time.Now().After(deadline) {
+			f.Close()
+			return nil, fmt.Errorf("%s: %w", path, ErrLockTimeout)
+		}
+		time.Sleep(lockRetryInterval)
+	}
+	return func() {
+		unlock(f)
+		f.Close()
+	}, nil
+}
@@ -1,6 +1,8 @@
 package config
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"flag"
 	"fmt"
 	"log"
@@ -8,6 +10,7 @@ import (
 	"path"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/BurntSushi/toml"
 	"github.com/adrg/xdg"
@@ -24,6 +27,24 @@ type ModelCost struct {
 	Input, Output float64
 }
 
+// Duration is a time.Duration decoded from a string such as "10s".
+type Duration time.Duration
+
+// UnmarshalText decodes a duration string into a Duration.
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// MarshalText encodes a Duration as a duration string.
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
 // StringOrSlice is a custom type that can hold either a string or a slice of strings.
 type StringOrSlice []string
 
@@ -55,11 +76,24 @@ func (s *StringOrSlice) UnmarshalTOML(data any) error {
 }
 
 type Source struct {
-	Name      string        `toml:"name"`
-	Collector StringOrSlice `toml:"collector"`
-	Previewer StringOrSlice `toml:"previewer"`
-	Prompt    string        `toml:"prompt"`
-	Enabled   bool          `toml:"enabled"`
+	Name          string        `toml:"name"`
+	Collector     StringOrSlice `toml:"collector"`
+	Previewer     StringOrSlice `toml:"previewer"`
+	Payload       StringOrSlice `toml:"payload"`
+	Prompt        string        `toml:"prompt"`
+	Enabled       bool          `toml:"enabled"`
+	Target        string        `toml:"target"`
+	Include       []string      `toml:"include"`
+	Exclude       []string      `toml:"exclude"`
+	MaxFileSize   int64         `toml:"max_file_size"`
+	MaxDepth      int           `toml:"max_depth"`
+	Format        string        `toml:"format"`
+	Timeout       Duration      `toml:"timeout"`
+	Watch         string        `toml:"watch"`
+	WatchInterval Duration      `toml:"watch_interval"`
+	Follow        bool          `toml:"follow"`
+	FollowLines   int           `toml:"follow_lines"`
+	Notes         string        `toml:"notes"`
 }
 
 func (i Source) Title() string {
@@ -81,40 +115,152 @@ func (i Source) String() string {
 		"Name: %s\n"+
 			"Collector: %s\n"+
 			"Previewer: %s\n"+
+			"Payload: %s\n"+
 			"Prompt: %s\n"+
-			"Enabled: %v",
+			"Enabled: %v\n"+
+			"Target: %s\n"+
+			"Include: %s\n"+
+			"Exclude: %s\n"+
+			"MaxFileSize: %d\n"+
+			"MaxDepth: %d\n"+
+			"Format: %s\n"+
+			"Timeout: %s\n"+
+			"Watch: %s\n"+
+			"WatchInterval: %s\n"+
+			"Follow: %v\n"+
+			"FollowLines: %d\n"+
+			"Notes: %s",
 		i.Name,
 		strings.Join(i.Collector, " "),
 		strings.Join(i.Previewer, " "),
+		strings.Join(i.Payload, " "),
 		i.Prompt,
 		i.Enabled,
+		i.Target,
+		strings.Join(i.Include, ", "),
+		strings.Join(i.Exclude, ", "),
+		i.MaxFileSize,
+		i.MaxDepth,
+		i.Format,
+		time.Duration(i.Timeout),
+		i.Watch,
+		time.Duration(i.WatchInterval),
+		i.Follow,
+		i.FollowLines,
+		i.Notes,
 	)
 }
 
+// Watch modes for refreshing items when the workspace changes.
+const (
+	WatchPoll   = "poll"
+	WatchNotify = "notify"
+)
+
+// Use of git notes by sources whose params are commits.
+const (
+	NotesWrite = "write" // Write reviews to notes
+	NotesRead  = "read"  // Load notes as reviews
+	NotesSync  = "sync"  // Both
+)
+
+// WritesNotes reports whether reviews of the source are written to git notes.
+func (i Source) WritesNotes() bool {
+	return i.Notes == NotesWrite || i.Notes == NotesSync
+}
+
+// ReadsNotes reports whether git notes are loaded as reviews of the source.
+func (i Source) ReadsNotes() bool {
+	return i.Notes == NotesRead || i.Notes == NotesSync
+}
+
+// Collector output formats.
+const (
+	FormatLines = "lines"
+	FormatJSONL = "jsonl"
+)
+
+// Locations of the review store of a project.
+const (
+	StoreProject = "project"
+	StoreRepo    = "repo"
+)
+
+// Formats of exported review reports.
+const (
+	ReportMarkdown = "markdown"
+	ReportHTML     = "html"
+	ReportSARIF    = "sarif"
+	ReportRDJSON   = "rdjson"
+	ReportBundle   = "bundle"
+)
+
 const projectName = "lazyreview"
 
+// Name of the directory in a repository holding the in-repo review store
+const RepoDirName = ".lazyreview"
+
+// DataPath returns the path of elem in the data directory of lazyreview.
+func DataPath(elem ...string) string {
+	return path.Join(append([]string{xdg.DataHome, projectName}, elem...)...)
+}
+
+// LegacyReviewStorePath returns the review file shared by all projects in older versions.
+func LegacyReviewStorePath() string {
+	return DataPath("reviews.json")
+}
+
+// ReviewStorePath returns the review file of the project at root.
+// An explicit output is used for every project. Otherwise reviews are stored in the
+// repository with the "repo" store, or in a directory of the project in the data directory.
+func (c Config) ReviewStorePath(root string) string {
+	if c.Output != "" {
+		return c.Output
+	}
+	if c.Store == StoreRepo {
+		return filepath.Join(root, RepoDirName, "reviews.json")
+	}
+	sum := sha256.Sum256([]byte(root))
+	return DataPath("projects", hex.EncodeToString(sum[:8]), "reviews.json")
+}
+
 // Config holds the configuration details for the application.
 type Config struct {
-	ConfigPath    string        `toml:"-"`
-	Key           string        `toml:"key"`
-	Endpoint      string        `toml:"endpoint"`
-	Version       string        `toml:"version"`
-	Model         string        `toml:"model"`
-	ModelCost     ModelCost     `toml:"modelCost"`
-	Target        string        `toml:"target"`
-	Output        string        `toml:"output"`
-	State         string        `toml:"state"`
-	Ignores       []string      `toml:"ignores"`
-	Prompt        string        `toml:"prompt"`
-	Type          string        `toml:"type"`
-	Collector     StringOrSlice `toml:"collector"`
-	Previewer     StringOrSlice `toml:"previewer"`
-	Glamour       string        `toml:"glamour"`
-	MaxTokens     int           `toml:"max_tokens"`
-	Opener        string        `toml:"opener"`
-	Sources       []Source      `toml:"sources"`
-	TmpReviewPath string        `toml:"-"`
-	TmpPromptPath string        `toml:"-"`
+	ConfigPath         string        `toml:"-"`
+	Key                string        `toml:"key"`
+	Endpoint           string        `toml:"endpoint"`
+	Version            string        `toml:"version"`
+	Model              string        `toml:"model"`
+	ModelCost          ModelCost     `toml:"modelCost"`
+	Target             string        `toml:"target"`
+	Output             string        `toml:"output"`
+	State              string        `toml:"state"`
+	Ignores            []string      `toml:"ignores"`
+	Prompt             string        `toml:"prompt"`
+	Type               string        `toml:"type"`
+	Collector          StringOrSlice `toml:"collector"`
+	Previewer          StringOrSlice `toml:"previewer"`
+	Glamour            string        `toml:"glamour"`
+	MaxTokens          int           `toml:"max_tokens"`
+	Opener             string        `toml:"opener"`
+	Sources            []Source      `toml:"sources"`
+	Watch              string        `toml:"watch"`
+	WatchInterval      Duration      `toml:"watch_interval"`
+	PreviewTheme       string        `toml:"preview_theme"`
+	PayloadLineNumbers bool          `toml:"payload_line_numbers"`
+	MaxPreviewSize     int64         `toml:"max_preview_size"`
+	MaxPayloadSize     int64         `toml:"max_payload_size"`
+	Vision             bool          `toml:"vision"`
+	NotebookOutputs    bool          `toml:"notebook_outputs"`
+	Store              string        `toml:"store"`
+	ReportFormat       string        `toml:"report_format"`
+	ReportPayload      bool          `toml:"report_payload"`
+	ReportDir          string        `toml:"report_dir"`
+	Author             string        `toml:"author"`
+	Sidecar            bool          `toml:"sidecar"`
+	TmpReviewPath      string        `toml:"-"`
+	TmpPromptPath      string        `toml:"-"`
+	WorkDir            string        `toml:"-"` // Directory commands run in. Empty is the working directory
 }
 
 // loadConfig reads the configuration from the specified file.
@@ -132,7 +278,7 @@ func NewConfig() Config {
 	c := Config{}
 	defaults := map[string]string{
 		"target": ".",
-		"output": path.Join(xdg.DataHome, projectName, "reviews.json"),
+		"output": "",
 		"model":  "gpt-4o-mini",
 	}
 
@@ -194,8 +340,8 @@ func loadConfigFile(c *Config) error {
 }
 
 func validateConfig(c *Config) {
-	if c.Target == "" || c.Output == "" || c.Model == "" {
-		log.Fatalf("Missing required configuration fields. Ensure `token`, `target`, `output`, and `model` are provided.")
+	if c.Target == "" || c.Model == "" {
+		log.Fatalf("Missing required configuration fields. Ensure `token`, `target`, and `model` are provided.")
 	}
 }
 
@@ -225,6 +371,20 @@ func (c Config) ToStringArray() []string {
 		fmt.Sprintf("max_tokens=%d", c.MaxTokens),
 		fmt.Sprintf("tmp_review_path=%s", c.TmpReviewPath),
 		fmt.Sprintf("opener=%s", c.Opener),
+		fmt.Sprintf("watch=%s", c.Watch),
+		fmt.Sprintf("watch_interval=%s", time.Duration(c.WatchInterval)),
+		fmt.Sprintf("preview_theme=%s", c.PreviewTheme),
+		fmt.Sprintf("payload_line_numbers=%v", c.PayloadLineNumbers),
+		fmt.Sprintf("max_preview_size=%d", c.MaxPreviewSize),
+		fmt.Sprintf("max_payload_size=%d", c.MaxPayloadSize),
+		fmt.Sprintf("vision=%v", c.Vision),
+		fmt.Sprintf("notebook_outputs=%v", c.NotebookOutputs),
+		fmt.Sprintf("store=%s", c.Store),
+		fmt.Sprintf("report_format=%s", c.ReportFormat),
+		fmt.Sprintf("report_payload=%v", c.ReportPayload),
+		fmt.Sprintf("report_dir=%s", c.ReportDir),
+		fmt.Sprintf("author=%s", c.Author),
+		fmt.Sprintf("sidecar=%v", c.Sidecar),
 		"\n",
 	)
 
@@ -0,0 +1,243 @@
+package ignore
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Ignore file names read from every walked directory, in order of precedence.
+var ignoreFileNames = []string{".gitignore", ".lazyreviewignore"}
+
+// Name of the git directory, which is always ignored
+const gitDirName = ".git"
+
+type pattern struct {
+	base    string
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// Matcher evaluates gitignore-style patterns collected from ignore files.
+type Matcher struct {
+	patterns      []pattern
+	alwaysIgnored []string
+	loaded        map[string]bool // Directories whose ignore files were read
+}
+
+// New creates a Matcher for walking root.
+// Patterns from .git/info/exclude and from ignore files in the directories between
+// the repository root and root are loaded up front. Ignore files inside root are
+// expected to be added with Load while walking.
+// Files and directories named gitDirName or one of alwaysIgnored are ignored at any depth.
+func New(root string, alwaysIgnored ...string) *Matcher {
+	m := &Matcher{
+		alwaysIgnored: append([]string{gitDirName}, alwaysIgnored...),
+		loaded:        map[string]bool{},
+	}
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return m
+	}
+
+	repoRoot := RepoRoot(absRoot)
+	if repoRoot == "" {
+		return m
+	}
+
+	m.loadFile(filepath.Join(repoRoot, gitDirName, "info", "exclude"), repoRoot)
+	rel, err := filepath.Rel(repoRoot, absRoot)
+	if err != nil || rel == "." {
+		return m
+	}
+	dir := repoRoot
+	m.Load(dir)
+	parts := strings.Split(rel, string(filepath.Separator))
+	for _, part := range parts[:len(parts)-1] {
+		dir = filepath.Join(dir, part)
+		m.Load(dir)
+	}
+	return m
+}
+
+// Load reads the ignore files located directly in dir. The files of a directory are
+// read once, so that walking a directory again does not add its patterns twice.
+func (m *Matcher) Load(dir string) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil || m.loaded[absDir] {
+		return
+	}
+	m.loaded[absDir] = true
+	for _, name := range ignoreFileNames {
+		m.loadFile(filepath.Join(absDir, name), absDir)
+	}
+}
+
+// Match reports whether path is ignored. The last matching pattern wins.
+func (m *Matcher) Match(path string, isDir bool) bool {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	base := filepath.Base(absPath)
+	for _, name := range m.alwaysIgnored {
+		if base == name {
+			return true
+		}
+	}
+
+	ignored := false
+	for _, p := range m.patterns {
+		if p.dirOnly && !isDir {
+			continue
+		}
+		rel, err := filepath.Rel(p.base, absPath)
+		if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		if p.re.MatchString(filepath.ToSlash(rel)) {
+			ignored = !p.negate
+		}
+	}
+	return ignored
+}
+
+func (m *Matcher) loadFile(filePath string, base string) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if p, ok := parsePattern(scanner.Text(), base); ok {
+			m.patterns = append(m.patterns, p)
+		}
+	}
+}
+
+// RepoRoot returns the nearest directory at or above dir containing .git,
+// or an empty string if dir is not inside a git repository.
+func RepoRoot(dir string) string {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	for {
+		if _, err := os.Stat(filepath.Join(dir, gitDirName)); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+func parsePattern(line string, base string) (pattern, bool) {
+	line = strings.TrimSuffix(line, "\r")
+	line = trimTrailingSpaces(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return pattern{}, false
+	}
+
+	p := pattern{base: base}
+	if strings.HasPrefix(line, "!") {
+		p.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		p.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return pattern{}, false
+	}
+
+	// A pattern containing a slash is relative to the directory of the ignore file,
+	// otherwise it matches at any depth.
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+
+	expr := globToRegexp(line)
+	if !anchored {
+		expr = "(?:.*/)?" + expr
+	}
+	re, err := regexp.Compile("^" + expr + "$")
+	if err != nil {
+		return pattern{}, false
+	}
+	p.re = re
+	return p, true
+}
+
+func trimTrailingSpaces(line string) string {
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, `\ `) {
+		line = line[:len(line)-1]
+	}
+	return line
+}
+
+func globToRegexp(glob string) string {
+	var sb strings.Builder
+	runes := []rune(glob)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch r {
+		case '*':
+			if i+1 < len(runes) && runes[i+1] == '*' {
+				atStart := i == 0 || runes[i-1] == '/'
+				atEnd := i+2 == len(runes)
+				if atStart && atEnd {
+					sb.WriteString(".*")
+					i++
+					continue
+				}
+				if atStart && runes[i+2] == '/' {
+					sb.WriteString("(?:.*/)?")
+					i += 2
+					continue
+				}
+			}
+			sb.WriteString("[^/]*")
+		case '?':
+			sb.WriteString("[^/]")
+		case '[':
+			end := indexRune(runes[i+1:], ']')
+			if end == -1 {
+				sb.WriteString(`\[`)
+				continue
+			}
+			class := string(runes[i+1 : i+1+end])
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			sb.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case '\\':
+			if i+1 < len(runes) {
+				i++
+				sb.WriteString(regexp.QuoteMeta(string(runes[i])))
+			}
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	return sb.String()
+}
+
+func indexRune(runes []rune, target rune) int {
+	for i, r := range runes {
+		if r == target {
+			return i
+		}
+	}
+	return -1
+}
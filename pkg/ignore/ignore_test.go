@@ -0,0 +1,70 @@
+package ignore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMatcherNegationAndDirOnly(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, ".gitignore"), "# comment\n*.log\n!keep.log\nbuild/\n/root-only.txt\ndocs/**/*.tmp\n\\!bang\n")
+	writeFile(t, filepath.Join(root, "sub", ".lazyreviewignore"), "!important.log\n")
+
+	m := New(root, ".lazyreview")
+	m.Load(root)
+	m.Load(filepath.Join(root, "sub"))
+
+	tests := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"a.log", false, true},
+		{"keep.log", false, false},
+		{"sub/b.log", false, true},
+		{"sub/important.log", false, false},
+		{"other/important.log", false, true},
+		{"build", true, true},
+		{"build", false, false},
+		{"sub/build", true, true},
+		{"root-only.txt", false, true},
+		{"sub/root-only.txt", false, false},
+		{"docs/a/b/c.tmp", false, true},
+		{"docs/c.tmp", false, true},
+		{"c.tmp", false, false},
+		{"!bang", false, true},
+		{"bang", false, false},
+		{"main.go", false, false},
+		{".git", true, true},
+		{"sub/.lazyreview", true, true},
+	}
+	for _, tt := range tests {
+		if got := m.Match(filepath.Join(root, filepath.FromSlash(tt.path)), tt.isDir); got != tt.want {
+			t.Errorf("Match(%q, %v) = %v, want %v", tt.path, tt.isDir, got, tt.want)
+		}
+	}
+}
+
+func writeFile(t *testing.T, path string, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMatcherLoadsDirectoriesOnce(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, ".gitignore"), "*.log\nbuild/\n")
+
+	m := New(root)
+	for i := 0; i < 3; i++ {
+		m.Load(root)
+	}
+	if len(m.patterns) != 2 {
+		t.Errorf("got %d patterns after loading the directory 3 times, want 2", len(m.patterns))
+	}
+}
@@ -43,24 +43,38 @@ func (c Client) Getreviewfromchatgpt(content string, conf config.Config) (*ai.Ch
 	if conf.Prompt != "" {
 		prompt = conf.Prompt
 	}
-	return reviewFromChatGPT(c, content, conf, prompt)
+	return reviewFromChatGPT(c, content, nil, conf, prompt)
 }
 
 // ChatGPT API呼び出し
 func (c Client) GetReviewFromChatGPTWithPrompt(content string, conf config.Config, prompt string) (*ai.ChatCompletion, error) {
-	return reviewFromChatGPT(c, content, conf, prompt)
+	return reviewFromChatGPT(c, content, nil, conf, prompt)
 }
 
-func reviewFromChatGPT(c Client, content string, conf config.Config, prompt string) (*ai.ChatCompletion, error) {
+// GetReviewFromChatGPTWithImages sends images along with the content.
+// Each image is a URL or a base64 data URL, and requires a vision-capable model.
+func (c Client) GetReviewFromChatGPTWithImages(content string, images []string, conf config.Config, prompt string) (*ai.ChatCompletion, error) {
+	return reviewFromChatGPT(c, content, images, conf, prompt)
+}
+
+func reviewFromChatGPT(c Client, content string, images []string, conf config.Config, prompt string) (*ai.ChatCompletion, error) {
 	maxTokens := 1000
 	if conf.MaxTokens != 0 {
 		maxTokens = conf.MaxTokens
 	}
+	var userMessage ai.ChatCompletionMessageParamUnion = ai.UserMessage(content)
+	if len(images) != 0 {
+		parts := []ai.ChatCompletionContentPartUnionParam{ai.TextPart(content)}
+		for _, image := range images {
+			parts = append(parts, ai.ImagePart(image))
+		}
+		userMessage = ai.UserMessageParts(parts...)
+	}
 	review, err := c.api.Chat.Completions.New(context.TODO(), ai.ChatCompletionNewParams{
 		Model: ai.F(c.conf.Model),
 		Messages: ai.F([]ai.ChatCompletionMessageParamUnion{
 			ai.SystemMessage(prompt),
-			ai.UserMessage(content),
+			userMessage,
 		}),
 		MaxTokens: ai.Int(int64(maxTokens)),
 	})
@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 	"strconv"
 
+	"github.com/shutils/lazyreview/pkg/atomicfile"
 	"github.com/shutils/lazyreview/pkg/config"
 )
 
@@ -65,11 +66,42 @@ func SaveState(filePath string, state State) {
 		log.Fatalf("Failed to create directories: %v", err)
 	}
 
-	if err = os.WriteFile(filePath, jsonData, 0644); err != nil {
+	if err = atomicfile.WriteFile(filePath, jsonData, 0644); err != nil {
 		log.Fatalf("Failed to save state: %v", err)
 	}
 }
 
+// Update applies update to the state stored in filePath and saves it. The file is locked and
+// read again first, so that the changes of other instances are kept.
+func Update(filePath string, update func(*State)) (State, error) {
+	unlock, err := atomicfile.Lock(filePath)
+	if err != nil {
+		return State{}, err
+	}
+	defer unlock()
+
+	s := State{}
+	data, err := os.ReadFile(filePath)
+	if err != nil && !os.IsNotExist(err) {
+		return State{}, err
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &s); err != nil {
+			return State{}, err
+		}
+	}
+	update(&s)
+
+	jsonData, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return State{}, err
+	}
+	if err := atomicfile.WriteFile(filePath, jsonData, 0644); err != nil {
+		return State{}, err
+	}
+	return s, nil
+}
+
 func SaveTmpReview(filePath string, review string) {
 	if filePath == "" {
 		log.Fatalf("File path is empty")
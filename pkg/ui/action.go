@@ -3,13 +3,15 @@ package ui
 import (
 	"os/exec"
 
-	"github.com/charmbracelet/bubbles/list"
 	tea "github.com/charmbracelet/bubbletea"
-	"github.com/shutils/lazyreview/pkg/config"
 	"github.com/shutils/lazyreview/pkg/state"
 )
 
 func (m *model) Quit() (tea.Model, tea.Cmd) {
+	m.stopFollowing()
+	if m.storage != nil {
+		m.storage.Close()
+	}
 	return *m, tea.Quit
 }
 
@@ -57,10 +59,7 @@ func (m *model) ToggleAiContext() (tea.Model, tea.Cmd) {
 }
 
 func (m *model) ReloadItems() (tea.Model, tea.Cmd) {
-	if m.panels.itemListPanel.model.FilterState() == list.Unfiltered {
-		m.panels.itemListPanel.model.SetItems(getItems(m.conf, m.reviewList))
-	}
-	return *m, nil
+	return *m, m.startCollecting()
 }
 
 func (m *model) ItemContentCursorDown() (tea.Model, tea.Cmd) {
@@ -83,6 +82,11 @@ func (m *model) ItemContentHalfViewUp() (tea.Model, tea.Cmd) {
 	return *m, nil
 }
 
+func (m *model) TogglePayloadView() (tea.Model, tea.Cmd) {
+	m.showPayload = !m.showPayload
+	return m.onChangeListSelectedItem()
+}
+
 func (m *model) ReviewContentCursorDown() (tea.Model, tea.Cmd) {
 	m.panels.itemReviewPanel.LineDown(1)
 	return *m, nil
@@ -171,6 +175,22 @@ func (m *model) FocusSourceListPanel() (tea.Model, tea.Cmd) {
 	return m.focusPanel(SourceListPanelFocus)
 }
 
+func (m *model) FocusCommandLogPanel() (tea.Model, tea.Cmd) {
+	m.panels.commandLogPanel.SetContent(m.commandLog.String())
+	m.panels.commandLogPanel.GotoTop()
+	return m.focusPanel(CommandLogPanelFocus)
+}
+
+func (m *model) CommandLogCursorDown() (tea.Model, tea.Cmd) {
+	m.panels.commandLogPanel.LineDown(1)
+	return *m, nil
+}
+
+func (m *model) CommandLogCursorUp() (tea.Model, tea.Cmd) {
+	m.panels.commandLogPanel.LineUp(1)
+	return *m, nil
+}
+
 func (m *model) ExitMessagePanel() (tea.Model, tea.Cmd) {
 	m.message = ""
 	return m.focusPanel(ItemListPanelFocus)
@@ -223,8 +243,11 @@ func (m *model) OpenCurrentReview() (tea.Model, tea.Cmd) {
 }
 
 func (m *model) ToggleSourceEnabled() (tea.Model, tea.Cmd) {
-	selectedItem := m.panels.sourceListPanel.SelectedItem().(config.Source)
-	m.conf.ToggleSourceEnabled(selectedItem.Name)
+	selectedItem, ok := m.panels.sourceListPanel.SelectedItem().(sourceItem)
+	if !ok {
+		return m, nil
+	}
+	m.conf.ToggleSourceEnabled(selectedItem.source.Name)
 	cmd := func() tea.Msg {
 		return updateSourceListMsg{}
 	}
@@ -239,11 +262,11 @@ func (m *model) DeleteReviewResult() (tea.Model, tea.Cmd) {
 			return SendErrorMessage("Failed to delete review:", nil)
 		}
 	}
-	m.deleteReview(item.id)
+	saveCmd := m.deleteReview(item.id)
 	index := m.panels.itemListPanel.model.Index()
-	m.changeItemTitlePrefix(index, "☐ ")
-	m.onChangeListSelectedItem()
-	return m, nil
+	m.changeItemTitlePrefix(index, unreviewedPrefix)
+	_, cmd := m.onChangeListSelectedItem()
+	return m, tea.Batch(saveCmd, cmd)
 }
 
 func (m *model) ToggleItemListViewStyle() (tea.Model, tea.Cmd) {
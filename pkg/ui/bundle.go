@@ -0,0 +1,228 @@
+package ui
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const (
+	// Format name identifying review bundles
+	bundleFormat = "lazyreview-bundle"
+	// Version of the bundle format written by this version
+	bundleVersion = 1
+)
+
+// Strategies of importing a bundle for items that already have a review
+const (
+	// Keep the versions of both, the newest one being the review
+	importMerge = "merge"
+	// Keep the local review and skip the imported one
+	importKeep = "keep"
+	// Replace the local review by the imported one
+	importReplace = "replace"
+)
+
+// reviewBundle is a portable set of reviews exchanged between developers of a project.
+type reviewBundle struct {
+	Format     string         `json:"format"`
+	Version    int            `json:"version"`
+	ExportedAt time.Time      `json:"exportedAt"`
+	ExportedBy string         `json:"exportedBy,omitempty"`
+	Project    string         `json:"project"` // Root of the project of the exporter, part of the item ids
+	Branch     string         `json:"branch,omitempty"`
+	Reviews    []bundleReview `json:"reviews"`
+}
+
+type bundleReview struct {
+	ID       string          `json:"id"`
+	Param    string          `json:"param"`
+	Key      string          `json:"key,omitempty"` // Collector id or path relative to the project root the id is made from
+	Source   string          `json:"source,omitempty"`
+	Author   string          `json:"author,omitempty"` // Author of the latest version
+	History  []reviewVersion `json:"history"`
+	Findings []finding       `json:"findings,omitempty"` // Findings of the latest version
+}
+
+// importResult counts the reviews of a bundle by what happened to them.
+type importResult struct {
+	added     int
+	unchanged int // Reviews already imported
+	merged    int
+	conflicts int // Merged reviews both sides changed
+	kept      int
+	replaced  int
+	skipped   int      // Reviews whose items could not be identified in this project
+	ids       []string // Reviews changed by the import
+}
+
+func (r importResult) String() string {
+	return fmt.Sprintf("%d added, %d unchanged, %d merged (%d conflicts), %d kept, %d replaced, %d skipped",
+		r.added, r.unchanged, r.merged, r.conflicts, r.kept, r.replaced, r.skipped)
+}
+
+// renderBundle writes the reviews of items as a bundle. Versions made before their
+// author was recorded are attributed to the exporter.
+func renderBundle(items []reportItem, root string, author string, now time.Time) (string, error) {
+	bundle := reviewBundle{
+		Format:     bundleFormat,
+		Version:    bundleVersion,
+		ExportedAt: now,
+		ExportedBy: author,
+		Project:    root,
+		Branch:     gitBranch(root),
+		Reviews:    []bundleReview{},
+	}
+	for _, item := range items {
+		history := []reviewVersion{}
+		for _, version := range item.review.versions() {
+			if version.Author == "" {
+				version.Author = author
+			}
+			history = append(history, version)
+		}
+		if len(history) == 0 {
+			continue
+		}
+		bundle.Reviews = append(bundle.Reviews, bundleReview{
+			ID:       item.review.ID,
+			Param:    item.review.Param,
+			Key:      item.review.Key,
+			Source:   item.review.Source,
+			Author:   history[len(history)-1].Author,
+			History:  history,
+			Findings: reviewFindings(item.review.Review),
+		})
+	}
+	return marshalReport(bundle)
+}
+
+func decodeBundle(data []byte) (reviewBundle, error) {
+	var bundle reviewBundle
+	if err := json.Unmarshal(data, &bundle); err != nil {
+		return bundle, err
+	}
+	if bundle.Format != bundleFormat {
+		return bundle, fmt.Errorf("not a review bundle")
+	}
+	if bundle.Version > bundleVersion {
+		return bundle, fmt.Errorf("bundle version %d is newer than the supported version %d", bundle.Version, bundleVersion)
+	}
+	return bundle, nil
+}
+
+// bundleReviews returns the reviews of a bundle with ids of the project at root.
+// Item ids depend on the project root of the exporter, so they are computed again
+// from the key of the items. Items of bundles exported without keys are identified
+// by their param, and keep their id if the roots are the same or are skipped otherwise.
+func bundleReviews(bundle reviewBundle, root string) ([]reviewInfo, int) {
+	ids := map[string]string{}
+	keys := map[string]string{}
+	for _, review := range bundle.Reviews {
+		key := review.Key
+		if key == "" {
+			key = review.Param
+		}
+		switch {
+		case itemID(bundle.Project, review.Source, key) == review.ID:
+			ids[review.ID] = itemID(root, review.Source, key)
+			keys[review.ID] = key
+		case bundle.Project == root:
+			ids[review.ID] = review.ID
+		}
+	}
+
+	var reviews []reviewInfo
+	skipped := 0
+	for _, review := range bundle.Reviews {
+		id, ok := ids[review.ID]
+		if !ok || len(review.History) == 0 {
+			skipped++
+			continue
+		}
+		info := reviewInfo{ID: id, Param: review.Param, Key: keys[review.ID], Source: review.Source, Project: root}
+		for _, version := range review.History {
+			if version.Author == "" {
+				version.Author = bundle.ExportedBy
+			}
+			contextIDs := make([]string, 0, len(version.ContextIDs))
+			for _, contextID := range version.ContextIDs {
+				if mapped, ok := ids[contextID]; ok {
+					contextIDs = append(contextIDs, mapped)
+				}
+			}
+			version.ContextIDs = contextIDs
+			info = info.addVersion(version)
+		}
+		reviews = append(reviews, info)
+	}
+	return reviews, skipped
+}
+
+// importBundle adds the reviews of bundle to storage. Items reviewed on both sides are
+// resolved by strategy. Merged reviews keep every version of both sides, and show the
+// newest one.
+func importBundle(storage reviewStorage, bundle reviewBundle, root string, strategy string) (importResult, error) {
+	var result importResult
+	switch strategy {
+	case "", importMerge, importKeep, importReplace:
+	default:
+		return result, fmt.Errorf("unknown import strategy %q", strategy)
+	}
+
+	reviews, skipped := bundleReviews(bundle, root)
+	result.skipped = skipped
+	stored, err := storage.Query(reviewQuery{Project: root})
+	if err != nil {
+		return result, err
+	}
+	existing := map[string]reviewInfo{}
+	for _, review := range stored {
+		existing[review.ID] = review
+	}
+
+	var imported []reviewInfo
+	for _, review := range reviews {
+		local, ok := existing[review.ID]
+		switch {
+		case !ok:
+			result.added++
+		case !hasOwnVersions(review, local):
+			result.unchanged++
+			continue
+		case strategy == importKeep:
+			result.kept++
+			continue
+		case strategy == importReplace:
+			if err := storage.Delete(review.ID); err != nil {
+				return result, err
+			}
+			result.replaced++
+		default:
+			result.merged++
+			if hasOwnVersions(local, review) {
+				result.conflicts++
+			}
+		}
+		imported = append(imported, review)
+		result.ids = append(result.ids, review.ID)
+	}
+	if len(imported) == 0 {
+		return result, nil
+	}
+	return result, storage.Import(imported)
+}
+
+// hasOwnVersions reports whether review has versions that other does not have.
+func hasOwnVersions(review reviewInfo, other reviewInfo) bool {
+	known := map[versionKey]bool{}
+	for _, version := range other.versions() {
+		known[version.key()] = true
+	}
+	for _, version := range review.versions() {
+		if !known[version.key()] {
+			return true
+		}
+	}
+	return false
+}
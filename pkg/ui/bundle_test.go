@@ -0,0 +1,238 @@
+package ui
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const (
+	bundleTestRoot    = "/us"
+	bundleTestProject = "/them"
+)
+
+var (
+	firstVersion    = reviewVersion{ReviewedAt: time.Unix(1, 0), Review: "first", PayloadHash: "1"}
+	localVersion    = reviewVersion{ReviewedAt: time.Unix(2, 0), Review: "local", PayloadHash: "2"}
+	exportedVersion = reviewVersion{ReviewedAt: time.Unix(3, 0), Review: "bundle", PayloadHash: "3"}
+)
+
+// testBundle returns a bundle exported from another project with an item of each kind:
+// one already imported, one changed on both sides, one changed by the exporter only,
+// one new, one identified by a collector id, and one exported without its key that
+// can not be mapped to this project.
+func testBundle() reviewBundle {
+	review := func(param string, key string, versions ...reviewVersion) bundleReview {
+		return bundleReview{ID: itemID(bundleTestProject, "", key), Param: param, Key: key, History: versions}
+	}
+	legacy := review("db", "container-2", exportedVersion)
+	legacy.Key = ""
+	return reviewBundle{
+		Format:     bundleFormat,
+		Version:    bundleVersion,
+		ExportedBy: "them",
+		Project:    bundleTestProject,
+		Reviews: []bundleReview{
+			review("same.go", "same.go", firstVersion),
+			review("both.go", "both.go", firstVersion, exportedVersion),
+			review("theirs.go", "theirs.go", firstVersion, exportedVersion),
+			review("new.go", "new.go", exportedVersion),
+			review("web", "container-1", exportedVersion),
+			legacy,
+		},
+	}
+}
+
+func testLocalReviews() []reviewInfo {
+	local := func(param string, versions ...reviewVersion) reviewInfo {
+		review := reviewInfo{ID: itemID(bundleTestRoot, "", param), Param: param, Project: bundleTestRoot}
+		for _, version := range versions {
+			review = review.addVersion(version)
+		}
+		return review
+	}
+	return []reviewInfo{
+		local("same.go", firstVersion),
+		local("both.go", firstVersion, localVersion),
+		local("theirs.go", firstVersion),
+	}
+}
+
+func TestImportBundle(t *testing.T) {
+	tests := []struct {
+		strategy    string
+		want        importResult
+		wantBoth    []string // Reviews of the versions of both.go after the import
+		wantTheirs  []string
+		wantChanged int
+		wantErr     bool
+	}{
+		{
+			strategy:    importMerge,
+			want:        importResult{added: 2, unchanged: 1, merged: 2, conflicts: 1, skipped: 1},
+			wantBoth:    []string{"first", "local", "bundle"},
+			wantTheirs:  []string{"first", "bundle"},
+			wantChanged: 4,
+		},
+		{
+			strategy:    "",
+			want:        importResult{added: 2, unchanged: 1, merged: 2, conflicts: 1, skipped: 1},
+			wantBoth:    []string{"first", "local", "bundle"},
+			wantTheirs:  []string{"first", "bundle"},
+			wantChanged: 4,
+		},
+		{
+			strategy:    importKeep,
+			want:        importResult{added: 2, unchanged: 1, kept: 2, skipped: 1},
+			wantBoth:    []string{"first", "local"},
+			wantTheirs:  []string{"first"},
+			wantChanged: 2,
+		},
+		{
+			strategy:    importReplace,
+			want:        importResult{added: 2, unchanged: 1, replaced: 2, skipped: 1},
+			wantBoth:    []string{"first", "bundle"},
+			wantTheirs:  []string{"first", "bundle"},
+			wantChanged: 4,
+		},
+		{
+			strategy: "theirs",
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		name := tt.strategy
+		if name == "" {
+			name = "default"
+		}
+		t.Run(name, func(t *testing.T) {
+			storage := &jsonStorage{path: filepath.Join(t.TempDir(), "reviews.json"), root: bundleTestRoot}
+			if err := storage.Import(testLocalReviews()); err != nil {
+				t.Fatal(err)
+			}
+
+			result, err := importBundle(storage, testBundle(), bundleTestRoot, tt.strategy)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(result.ids) != tt.wantChanged {
+				t.Errorf("changed %d reviews, want %d", len(result.ids), tt.wantChanged)
+			}
+			result.ids = nil
+			if result.String() != tt.want.String() {
+				t.Errorf("result = %v, want %v", result, tt.want)
+			}
+
+			stored, err := storage.Load()
+			if err != nil {
+				t.Fatal(err)
+			}
+			reviews := map[string]reviewInfo{}
+			for _, review := range stored {
+				reviews[review.Param] = review
+			}
+			checkVersions(t, reviews["both.go"], tt.wantBoth)
+			checkVersions(t, reviews["theirs.go"], tt.wantTheirs)
+			checkVersions(t, reviews["same.go"], []string{"first"})
+			checkVersions(t, reviews["new.go"], []string{"bundle"})
+			checkVersions(t, reviews["web"], []string{"bundle"})
+			if id := itemID(bundleTestRoot, "", "container-1"); reviews["web"].ID != id || reviews["web"].Key != "container-1" {
+				t.Errorf("web id = %q, key %q, want %q from its collector id", reviews["web"].ID, reviews["web"].Key, id)
+			}
+			if _, ok := reviews["db"]; ok {
+				t.Errorf("review of an unmapped item was imported")
+			}
+			if author := latestVersion(reviews["new.go"]).Author; author != "them" {
+				t.Errorf("author = %q, want the exporter", author)
+			}
+		})
+	}
+}
+
+func checkVersions(t *testing.T, review reviewInfo, want []string) {
+	t.Helper()
+	versions := review.versions()
+	if len(versions) != len(want) {
+		t.Errorf("%s has %d versions, want %v", review.Param, len(versions), want)
+		return
+	}
+	for i, version := range versions {
+		if version.Review != want[i] {
+			t.Errorf("%s version %d = %q, want %q", review.Param, i, version.Review, want[i])
+		}
+	}
+	if review.Review != want[len(want)-1] {
+		t.Errorf("%s shows %q, want the newest version %q", review.Param, review.Review, want[len(want)-1])
+	}
+}
+
+func TestBundleReviewsIDs(t *testing.T) {
+	bundle := testBundle()
+	contextID := bundle.Reviews[0].ID
+	version := exportedVersion
+	version.ContextIDs = []string{contextID, bundle.Reviews[4].ID}
+	bundle.Reviews[3].History = []reviewVersion{version}
+
+	tests := []struct {
+		name        string
+		root        string
+		wantSkipped int
+		wantIDs     map[string]string // By param
+		wantContext []string          // Context ids of new.go
+	}{
+		{
+			name:        "other project",
+			root:        bundleTestRoot,
+			wantSkipped: 1,
+			wantIDs: map[string]string{
+				"same.go": itemID(bundleTestRoot, "", "same.go"),
+				"new.go":  itemID(bundleTestRoot, "", "new.go"),
+				"web":     itemID(bundleTestRoot, "", "container-1"),
+			},
+			wantContext: []string{itemID(bundleTestRoot, "", "same.go"), itemID(bundleTestRoot, "", "container-1")},
+		},
+		{
+			name:        "same project",
+			root:        bundleTestProject,
+			wantSkipped: 0,
+			wantIDs: map[string]string{
+				"same.go": contextID,
+				"web":     bundle.Reviews[4].ID,
+				"db":      bundle.Reviews[5].ID,
+			},
+			wantContext: []string{contextID, bundle.Reviews[4].ID},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reviews, skipped := bundleReviews(bundle, tt.root)
+			if skipped != tt.wantSkipped {
+				t.Errorf("skipped = %d, want %d", skipped, tt.wantSkipped)
+			}
+			ids := map[string]reviewInfo{}
+			for _, review := range reviews {
+				ids[review.Param] = review
+				if review.Project != tt.root {
+					t.Errorf("%s project = %q, want %q", review.Param, review.Project, tt.root)
+				}
+			}
+			for param, id := range tt.wantIDs {
+				if ids[param].ID != id {
+					t.Errorf("%s id = %q, want %q", param, ids[param].ID, id)
+				}
+			}
+			got := ids["new.go"].History[0].ContextIDs
+			if len(got) != len(tt.wantContext) {
+				t.Fatalf("context ids = %v, want %v", got, tt.wantContext)
+			}
+			for i := range got {
+				if got[i] != tt.wantContext[i] {
+					t.Errorf("context ids = %v, want %v", got, tt.wantContext)
+				}
+			}
+		})
+	}
+}
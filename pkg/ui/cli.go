@@ -0,0 +1,151 @@
+package ui
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/shutils/lazyreview/pkg/atomicfile"
+	"github.com/shutils/lazyreview/pkg/config"
+)
+
+// RunCommand runs a subcommand given on the command line instead of the UI.
+func RunCommand(conf config.Config, args []string) error {
+	switch args[0] {
+	case "export":
+		return runExport(conf, args[1:], os.Stdout)
+	case "import":
+		return runImport(conf, args[1:], os.Stdout)
+	default:
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+}
+
+// runExport writes a report of the reviews of the current project.
+func runExport(conf config.Config, args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	formatName := fs.String("format", conf.ReportFormat, "Report format: markdown, html, sarif, rdjson or bundle")
+	output := fs.String("o", "", "File to write the report to. Defaults to stdout")
+	payload := fs.Bool("payload", conf.ReportPayload, "Include the reviewed payloads")
+	source := fs.String("source", "", "Only export the reviews of this source")
+	id := fs.String("id", "", "Only export the review of the item with this id")
+	since := fs.String("since", "", "Only export reviews made on or after this date (YYYY-MM-DD)")
+	until := fs.String("until", "", "Only export reviews made before this date (YYYY-MM-DD)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	format, err := reportFormat(*formatName)
+	if err != nil {
+		return err
+	}
+	root := projectRoot(conf.Target)
+	q := reviewQuery{ID: *id, Source: *source, Project: root}
+	if q.Since, err = parseDate(*since); err != nil {
+		return err
+	}
+	if q.Until, err = parseDate(*until); err != nil {
+		return err
+	}
+
+	storage, err := openStorage(conf.ReviewStorePath(root), conf.State, root, sourceNames(conf.Sources))
+	if err != nil {
+		return err
+	}
+	defer storage.Close()
+	reviews, err := storage.Query(q)
+	if err != nil {
+		return err
+	}
+	sort.SliceStable(reviews, func(i, j int) bool {
+		return reviews[i].Param < reviews[j].Param
+	})
+
+	items := []reportItem{}
+	for _, review := range reviews {
+		review, ok := finishedReview(review)
+		if !ok {
+			continue
+		}
+		items = append(items, reportItem{
+			title:  review.Param,
+			item:   listItem{param: review.Param, sourceName: review.Source, id: review.ID},
+			review: review,
+		})
+	}
+	if *payload {
+		loadReportPayloads(context.Background(), items, conf, newCommandLog())
+	}
+	report, err := renderReport(items, root, conf, format, time.Now())
+	if err != nil {
+		return err
+	}
+	if *output == "" || *output == "-" {
+		_, err = io.WriteString(stdout, report)
+		return err
+	}
+	return atomicfile.WriteFile(*output, []byte(report), 0644)
+}
+
+// runImport imports review bundles exported by other developers into the current project.
+func runImport(conf config.Config, args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	strategy := fs.String("strategy", importMerge, "How to import reviews of items already reviewed: merge, keep or replace")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("no bundle to import")
+	}
+
+	root := projectRoot(conf.Target)
+	storage, err := openStorage(conf.ReviewStorePath(root), conf.State, root, sourceNames(conf.Sources))
+	if err != nil {
+		return err
+	}
+	defer storage.Close()
+	branch := gitBranch(root)
+	for _, path := range fs.Args() {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		bundle, err := decodeBundle(data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if bundle.Branch != "" && branch != "" && bundle.Branch != branch {
+			fmt.Fprintf(stdout, "%s: reviews of branch %s imported into branch %s\n", path, bundle.Branch, branch)
+		}
+		result, err := importBundle(storage, bundle, root, *strategy)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		fmt.Fprintf(stdout, "%s: %s\n", path, result)
+		if conf.Sidecar {
+			for _, id := range result.ids {
+				reviews, err := storage.Query(reviewQuery{ID: id})
+				if err != nil {
+					return err
+				}
+				for _, review := range reviews {
+					if err := writeSidecar(root, review); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func parseDate(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.ParseInLocation("2006-01-02", value, time.Local)
+}
@@ -1,69 +1,198 @@
 package ui
 
 import (
-	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
 	"io/fs"
-	"log"
-	"os/exec"
 	"path/filepath"
 	"regexp"
 	"strings"
+	"time"
 
+	"github.com/bmatcuk/doublestar/v4"
 	"github.com/charmbracelet/bubbles/list"
 	"github.com/shutils/lazyreview/pkg/config"
+	"github.com/shutils/lazyreview/pkg/ignore"
 )
 
-func defaultItemCollector(conf config.Config, sourceName string) []list.Item {
+// newIgnoreMatcher returns the ignore matcher for walking root. The lazyreview directory
+// holding the review store and review files is ignored along with the git directory.
+func newIgnoreMatcher(root string) *ignore.Matcher {
+	return ignore.New(root, config.RepoDirName)
+}
+
+func defaultItemCollector(ctx context.Context, conf config.Config, source config.Source) ([]list.Item, commandResult) {
 	items := []list.Item{}
+	target := sourceTarget(conf, source)
+	result := commandResult{
+		kind:      "collector",
+		source:    source.Name,
+		args:      []string{"walk", target},
+		startedAt: time.Now(),
+	}
 	compiledPatterns := make([]*regexp.Regexp, len(conf.Ignores))
 	for i, p := range conf.Ignores {
-		compiledPatterns[i] = regexp.MustCompile(p)
+		re, err := regexp.Compile(p)
+		if err != nil {
+			result.err = fmt.Errorf("invalid ignore pattern %q: %w", p, err)
+			result.exitCode = 1
+			return items, result
+		}
+		compiledPatterns[i] = re
 	}
-	err := filepath.WalkDir(conf.Target, func(path string, d fs.DirEntry, err error) error {
+	matcher := newIgnoreMatcher(target)
+	err := filepath.WalkDir(target, func(path string, d fs.DirEntry, err error) error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			if path == target {
+				return err
+			}
+			// Skip unreadable entries but keep walking the rest of the tree
+			result.stderr += err.Error() + "\n"
+			if d != nil && d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if path == target {
+			matcher.Load(path)
+			return nil
+		}
+		rel, err := filepath.Rel(target, path)
 		if err != nil {
 			return err
 		}
-		if !d.IsDir() {
-			// 絞り込み処理
-			for _, re := range compiledPatterns {
-				if re.MatchString(path) {
-					return nil
-				}
+		rel = filepath.ToSlash(rel)
+		if matcher.Match(path, d.IsDir()) || matchGlobs(source.Exclude, rel) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		depth := strings.Count(rel, "/") + 1
+		if d.IsDir() {
+			if source.MaxDepth > 0 && depth >= source.MaxDepth {
+				return filepath.SkipDir
+			}
+			matcher.Load(path)
+			return nil
+		}
+		// 絞り込み処理
+		for _, re := range compiledPatterns {
+			if re.MatchString(path) {
+				return nil
 			}
-			items = append(items, listItem{title: d.Name(), param: path, sourceName: sourceName})
 		}
+		if len(source.Include) != 0 && !matchGlobs(source.Include, rel) {
+			return nil
+		}
+		if source.MaxFileSize > 0 {
+			info, err := d.Info()
+			if err != nil || info.Size() > source.MaxFileSize {
+				return nil
+			}
+		}
+		items = append(items, listItem{title: d.Name(), param: path, sourceName: source.Name})
 		return nil
 	})
+	result.duration = time.Since(result.startedAt)
 	if err != nil {
-		log.Fatal(err)
+		result.err = err
+		result.exitCode = 1
 	}
-	return items
+	return items, result
 }
 
-func customCollector(cmds []string, sourceName string) []list.Item {
-	if len(cmds) == 0 {
-		return []list.Item{}
+// sourceTarget returns the directory a source collects items from.
+// Relative source targets are relative to the directory commands run in.
+func sourceTarget(conf config.Config, source config.Source) string {
+	if source.Target == "" {
+		return conf.Target
 	}
-	items := []list.Item{}
-	args := cmds[1:]
-	cmd := exec.Command(cmds[0], args...)
+	if conf.WorkDir != "" && !filepath.IsAbs(source.Target) {
+		return filepath.Join(conf.WorkDir, source.Target)
+	}
+	return source.Target
+}
+
+func matchGlobs(patterns []string, path string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := doublestar.Match(pattern, path); ok {
+			return true
+		}
+	}
+	return false
+}
 
-	var stdout bytes.Buffer
-	cmd.Stdout = &stdout
+// collectedItem is a single line of a collector's JSON lines output.
+type collectedItem struct {
+	Title       string         `json:"title"`
+	Param       string         `json:"param"`
+	Description string         `json:"description"`
+	Group       string         `json:"group"`
+	ID          string         `json:"id"`
+	Meta        map[string]any `json:"meta"`
+}
 
-	err := cmd.Run()
-	if err != nil {
-		return []list.Item{}
+func customCollector(ctx context.Context, cmds []string, sourceName string, format string, dir string, vars commandVars) ([]list.Item, commandResult) {
+	items := []list.Item{}
+	args, _ := vars.expand(cmds)
+	result := runCommand(ctx, "collector", sourceName, dir, args, vars)
+	if result.failed() {
+		return items, result
 	}
 
-	output := stdout.String()
-	if output == "" {
-		return []list.Item{}
+	output := result.stdout
+	if strings.TrimSpace(output) == "" {
+		return items, result
 	}
 
 	paramStrings := strings.Split(strings.TrimSpace(output), "\n")
 	for _, param := range paramStrings {
+		if format == config.FormatJSONL {
+			if item, ok := parseCollectedItem(param, sourceName); ok {
+				items = append(items, item)
+			}
+			continue
+		}
 		items = append(items, listItem{title: filepath.Base(param), param: param, sourceName: sourceName})
 	}
-	return items
+	return items, result
+}
+
+// parseCollectedItem converts a JSON line into a listItem.
+// Lines that are not valid JSON are treated as a plain param.
+func parseCollectedItem(line string, sourceName string) (listItem, bool) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return listItem{}, false
+	}
+	var c collectedItem
+	if err := json.Unmarshal([]byte(line), &c); err != nil {
+		return listItem{title: filepath.Base(line), param: line, sourceName: sourceName}, true
+	}
+	if c.Param == "" {
+		c.Param = c.ID
+	}
+	if c.Param == "" {
+		c.Param = c.Title
+	}
+	if c.Param == "" {
+		return listItem{}, false
+	}
+	if c.Title == "" {
+		c.Title = filepath.Base(c.Param)
+	}
+	return listItem{
+		title:       c.Title,
+		param:       c.Param,
+		sourceName:  sourceName,
+		key:         c.ID,
+		description: c.Description,
+		group:       c.Group,
+		meta:        c.Meta,
+	}, true
 }
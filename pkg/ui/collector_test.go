@@ -0,0 +1,56 @@
+package ui
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseCollectedItem(t *testing.T) {
+	tests := []struct {
+		name   string
+		line   string
+		want   listItem
+		wantOK bool
+	}{
+		{
+			name:   "all fields",
+			line:   `{"title": "web", "param": "abc123", "description": "nginx", "group": "prod", "id": "c-1", "meta": {"port": 80}}`,
+			want:   listItem{title: "web", param: "abc123", sourceName: "docker", key: "c-1", description: "nginx", group: "prod", meta: map[string]any{"port": float64(80)}},
+			wantOK: true,
+		},
+		{
+			name:   "title from the param",
+			line:   `{"param": "pkg/main.go"}`,
+			want:   listItem{title: "main.go", param: "pkg/main.go", sourceName: "docker"},
+			wantOK: true,
+		},
+		{
+			name:   "param from the id",
+			line:   `{"id": "c-1"}`,
+			want:   listItem{title: "c-1", param: "c-1", sourceName: "docker", key: "c-1"},
+			wantOK: true,
+		},
+		{
+			name:   "param from the title",
+			line:   `{"title": "web"}`,
+			want:   listItem{title: "web", param: "web", sourceName: "docker"},
+			wantOK: true,
+		},
+		{
+			name:   "plain line",
+			line:   "  pkg/main.go  ",
+			want:   listItem{title: "main.go", param: "pkg/main.go", sourceName: "docker"},
+			wantOK: true,
+		},
+		{name: "no param", line: `{"description": "nothing"}`},
+		{name: "blank line", line: "   "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseCollectedItem(tt.line, "docker")
+			if ok != tt.wantOK || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseCollectedItem() = %+v, %v, want %+v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,233 @@
+package ui
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+const (
+	commandLogSize = 50
+	// Bytes of stderr kept from streamed commands, which can run for as long as they are followed
+	streamStderrSize = 64 << 10
+	// Time to wait for the output pipes after a command is killed by its context
+	commandWaitDelay = time.Second
+)
+
+// commandResult holds the outcome of an external command run by a collector or previewer.
+type commandResult struct {
+	kind      string
+	source    string
+	args      []string
+	stdout    string
+	stderr    string
+	exitCode  int
+	duration  time.Duration
+	startedAt time.Time
+	err       error
+}
+
+func (r commandResult) failed() bool {
+	return r.err != nil
+}
+
+func (r commandResult) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "[%s] %s", r.startedAt.Format("15:04:05"), r.kind)
+	if r.source != "" {
+		fmt.Fprintf(&sb, " (%s)", r.source)
+	}
+	fmt.Fprintf(&sb, "\nCommand: %s", strings.Join(r.args, " "))
+	fmt.Fprintf(&sb, "\nExit code: %d", r.exitCode)
+	fmt.Fprintf(&sb, "\nDuration: %s", r.duration.Round(time.Millisecond))
+	if r.err != nil {
+		fmt.Fprintf(&sb, "\nError: %v", r.err)
+	}
+	if stderr := strings.TrimSpace(r.stderr); stderr != "" {
+		fmt.Fprintf(&sb, "\nStderr:\n%s", stderr)
+	}
+	return sb.String()
+}
+
+// commandVars are the values substituted for placeholders such as {param} in collector
+// and previewer commands. They are also exported to the commands as LAZYREVIEW_* variables.
+type commandVars map[string]string
+
+// expand replaces the placeholders in cmds and reports whether any placeholder was found.
+// Each argument is replaced in a single pass, so values containing placeholders are kept as is.
+func (v commandVars) expand(cmds []string) ([]string, bool) {
+	names := make([]string, 0, len(v))
+	for name := range v {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	oldnew := make([]string, 0, 2*len(names))
+	for _, name := range names {
+		oldnew = append(oldnew, "{"+name+"}", v[name])
+	}
+	replacer := strings.NewReplacer(oldnew...)
+
+	expanded := make([]string, len(cmds))
+	used := false
+	for i, arg := range cmds {
+		for _, name := range names {
+			if strings.Contains(arg, "{"+name+"}") {
+				used = true
+				break
+			}
+		}
+		expanded[i] = replacer.Replace(arg)
+	}
+	return expanded, used
+}
+
+// environ returns the environment of the current process with the variables appended.
+func (v commandVars) environ() []string {
+	env := os.Environ()
+	for name, value := range v {
+		env = append(env, "LAZYREVIEW_"+envName(name)+"="+value)
+	}
+	return env
+}
+
+func envName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToUpper(r)
+		}
+		return '_'
+	}, name)
+}
+
+// itemCommandVars returns the placeholder values of an item.
+// {id} is the id given by the collector, or the param of items without one.
+// Meta fields of the item are available as {meta.<key>}.
+func itemCommandVars(item listItem, target string) commandVars {
+	id := item.key
+	if id == "" {
+		id = item.param
+	}
+	vars := commandVars{
+		"param":  item.param,
+		"title":  item.plainTitle(),
+		"source": item.sourceName,
+		"target": target,
+		"id":     id,
+	}
+	for key, value := range item.meta {
+		vars["meta."+key] = fmt.Sprint(value)
+	}
+	return vars
+}
+
+// runCommand runs cmds in dir and captures its output, exit code and duration.
+// An empty dir is the working directory. The command is killed when ctx is done.
+func runCommand(ctx context.Context, kind string, source string, dir string, cmds []string, vars commandVars) commandResult {
+	var stdout, stderr bytes.Buffer
+	result := execCommand(ctx, kind, source, dir, cmds, vars, &stdout, &stderr)
+	result.stdout = stdout.String()
+	result.stderr = stderr.String()
+	return result
+}
+
+// streamCommand runs cmds like runCommand, but writes its stdout and stderr to w as they are produced.
+// Only the tail of stderr is kept in the result.
+func streamCommand(ctx context.Context, kind string, source string, dir string, cmds []string, vars commandVars, w io.Writer) commandResult {
+	stderr := &tailBuffer{size: streamStderrSize}
+	result := execCommand(ctx, kind, source, dir, cmds, vars, w, io.MultiWriter(w, stderr))
+	result.stderr = string(stderr.buf)
+	return result
+}
+
+// tailBuffer keeps the last size bytes written to it.
+type tailBuffer struct {
+	buf  []byte
+	size int
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	if len(p) >= b.size {
+		b.buf = append(b.buf[:0], p[len(p)-b.size:]...)
+		return len(p), nil
+	}
+	if over := len(b.buf) + len(p) - b.size; over > 0 {
+		b.buf = append(b.buf[:0], b.buf[over:]...)
+	}
+	b.buf = append(b.buf, p...)
+	return len(p), nil
+}
+
+func execCommand(ctx context.Context, kind string, source string, dir string, cmds []string, vars commandVars, stdout io.Writer, stderr io.Writer) commandResult {
+	result := commandResult{
+		kind:      kind,
+		source:    source,
+		args:      cmds,
+		startedAt: time.Now(),
+	}
+	if len(cmds) == 0 {
+		result.err = errors.New("no command")
+		return result
+	}
+
+	cmd := exec.CommandContext(ctx, cmds[0], cmds[1:]...)
+	cmd.WaitDelay = commandWaitDelay
+	cmd.Dir = dir
+	cmd.Env = vars.environ()
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	result.err = cmd.Run()
+	result.duration = time.Since(result.startedAt)
+	if cmd.ProcessState != nil {
+		result.exitCode = cmd.ProcessState.ExitCode()
+	} else if result.err != nil {
+		result.exitCode = -1
+	}
+	return result
+}
+
+// commandLog keeps the most recent failed commands.
+// It is shared between the model copies and the review goroutines.
+type commandLog struct {
+	mu      sync.Mutex
+	entries []commandResult
+}
+
+func newCommandLog() *commandLog {
+	return &commandLog{}
+}
+
+func (l *commandLog) add(result commandResult) {
+	if !result.failed() {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = append(l.entries, result)
+	if len(l.entries) > commandLogSize {
+		l.entries = l.entries[len(l.entries)-commandLogSize:]
+	}
+}
+
+func (l *commandLog) String() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.entries) == 0 {
+		return "No command errors"
+	}
+	entries := make([]string, len(l.entries))
+	for i, entry := range l.entries {
+		// Newest first
+		entries[len(l.entries)-1-i] = entry.String()
+	}
+	return strings.Join(entries, "\n\n")
+}
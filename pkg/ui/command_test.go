@@ -0,0 +1,52 @@
+package ui
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCommandVarsExpand(t *testing.T) {
+	vars := commandVars{
+		"param":    "{id}",
+		"id":       "c-1",
+		"meta.env": "prod",
+	}
+	tests := []struct {
+		name     string
+		cmds     []string
+		want     []string
+		wantUsed bool
+	}{
+		{"no placeholder", []string{"cat"}, []string{"cat"}, false},
+		{"placeholders", []string{"docker", "logs", "{id}", "--env={meta.env}"}, []string{"docker", "logs", "c-1", "--env=prod"}, true},
+		{"values are not expanded again", []string{"echo", "{param}"}, []string{"echo", "{id}"}, true},
+		{"unknown placeholder", []string{"echo", "{other}"}, []string{"echo", "{other}"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, used := vars.expand(tt.cmds)
+			if !reflect.DeepEqual(got, tt.want) || used != tt.wantUsed {
+				t.Errorf("expand(%q) = %q, %v, want %q, %v", tt.cmds, got, used, tt.want, tt.wantUsed)
+			}
+		})
+	}
+}
+
+func TestCommandVarsEnviron(t *testing.T) {
+	env := commandVars{"meta.env": "prod", "param": "main.go"}.environ()
+	for _, want := range []string{"LAZYREVIEW_META_ENV=prod", "LAZYREVIEW_PARAM=main.go"} {
+		found := false
+		for _, v := range env {
+			if v == want {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("%s not in the environment", want)
+		}
+	}
+	if !strings.HasPrefix(strings.Join(env, "\n"), strings.Join(commandVars{}.environ(), "\n")) {
+		t.Errorf("the environment of the process is not kept")
+	}
+}
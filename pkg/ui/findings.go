@@ -0,0 +1,93 @@
+package ui
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Levels of findings, as named by SARIF
+const (
+	levelError   = "error"
+	levelWarning = "warning"
+	levelNote    = "note"
+)
+
+var jsonBlockRegexp = regexp.MustCompile("(?s)```json[ \\t]*\\r?\\n(.*?)\\r?\\n[ \\t]*```")
+
+// finding is an issue reported by a review at a location of the reviewed item.
+// Reviews report findings in a ```json block holding an array of findings,
+// or an object with the array in "findings".
+type finding struct {
+	Path     string `json:"path,omitempty"` // Defaults to the reviewed item
+	Line     int    `json:"line,omitempty"`
+	EndLine  int    `json:"end_line,omitempty"`
+	Column   int    `json:"column,omitempty"`
+	Severity string `json:"severity,omitempty"`
+	Rule     string `json:"rule,omitempty"`
+	Message  string `json:"message"`
+}
+
+// reviewFindings returns the findings reported in the JSON blocks of a review.
+func reviewFindings(review string) []finding {
+	var findings []finding
+	for _, match := range jsonBlockRegexp.FindAllStringSubmatch(review, -1) {
+		block := []byte(match[1])
+		var list []finding
+		if err := json.Unmarshal(block, &list); err != nil {
+			var wrapped struct {
+				Findings []finding `json:"findings"`
+			}
+			if err := json.Unmarshal(block, &wrapped); err != nil {
+				continue
+			}
+			list = wrapped.Findings
+		}
+		for _, f := range list {
+			if strings.TrimSpace(f.Message) != "" {
+				findings = append(findings, f)
+			}
+		}
+	}
+	return findings
+}
+
+// findingLevel maps the severity named by a review to a level.
+// Unknown severities are warnings.
+func findingLevel(severity string) string {
+	switch strings.ToLower(strings.TrimSpace(severity)) {
+	case "error", "critical", "blocker", "high", "fatal":
+		return levelError
+	case "note", "info", "low", "minor", "suggestion", "nit", "hint":
+		return levelNote
+	default:
+		return levelWarning
+	}
+}
+
+// repoRelativePath returns the slash separated path of param relative to the repository root,
+// if param is a file in the repository. Relative params are relative to the working directory
+// like the commands of the sources.
+func repoRelativePath(param string, root string) (string, bool) {
+	if param == "" {
+		return "", false
+	}
+	path := param
+	if !filepath.IsAbs(path) {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return "", false
+		}
+		path = abs
+	}
+	if info, err := os.Stat(path); err != nil || info.IsDir() {
+		return "", false
+	}
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
@@ -0,0 +1,170 @@
+package ui
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestReviewFindings(t *testing.T) {
+	tests := []struct {
+		name   string
+		review string
+		want   []finding
+	}{
+		{"no block", "Looks good.", nil},
+		{"other language", "```go\n[{\"message\": \"x\"}]\n```", nil},
+		{
+			"array",
+			"Issues:\n\n```json\n[{\"line\": 3, \"severity\": \"high\", \"message\": \"nil dereference\"}]\n```\n",
+			[]finding{{Line: 3, Severity: "high", Message: "nil dereference"}},
+		},
+		{
+			"object",
+			"```json\n{\"findings\": [{\"path\": \"a.go\", \"line\": 1, \"end_line\": 2, \"column\": 5, \"rule\": \"naming\", \"message\": \"rename\"}]}\n```",
+			[]finding{{Path: "a.go", Line: 1, EndLine: 2, Column: 5, Rule: "naming", Message: "rename"}},
+		},
+		{"crlf", "```json\r\n[{\"message\": \"windows\"}]\r\n```", []finding{{Message: "windows"}}},
+		{"invalid json", "```json\n[{\"message\": }]\n```", nil},
+		{"without message", "```json\n[{\"line\": 1}, {\"line\": 2, \"message\": \"  \"}]\n```", nil},
+		{
+			"several blocks",
+			"```json\n[{\"message\": \"one\"}]\n```\ntext\n```json\n{\"findings\": [{\"message\": \"two\"}]}\n```",
+			[]finding{{Message: "one"}, {Message: "two"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := reviewFindings(tt.review); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("reviewFindings() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindingLevel(t *testing.T) {
+	tests := []struct {
+		severity string
+		want     string
+	}{
+		{"error", levelError},
+		{" Critical ", levelError},
+		{"high", levelError},
+		{"warning", levelWarning},
+		{"medium", levelWarning},
+		{"", levelWarning},
+		{"nit", levelNote},
+		{"INFO", levelNote},
+	}
+	for _, tt := range tests {
+		if got := findingLevel(tt.severity); got != tt.want {
+			t.Errorf("findingLevel(%q) = %q, want %q", tt.severity, got, tt.want)
+		}
+	}
+}
+
+func TestReportSARIF(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"main.go", "util.go"} {
+		if err := os.WriteFile(filepath.Join(root, name), []byte("package main\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	review := func(id string, param string, text string) reportItem {
+		info := reviewInfo{ID: id, Param: param}.addVersion(reviewVersion{ReviewedAt: time.Unix(1, 0), Model: "m", PayloadHash: "h", Review: text})
+		return reportItem{title: param, review: info}
+	}
+	items := []reportItem{
+		review("1", filepath.Join(root, "main.go"), "```json\n["+
+			"{\"line\": 3, \"end_line\": 5, \"column\": 2, \"severity\": \"critical\", \"message\": \"bug\"},"+
+			"{\"path\": \"util.go\", \"severity\": \"nit\", \"rule\": \"style\", \"message\": \"style\"},"+
+			"{\"path\": \"missing.go\", \"line\": 1, \"message\": \"elsewhere\"}"+
+			"]\n```"),
+		review("2", filepath.Join(root, "util.go"), "Looks good."),
+		review("3", "container-1", "Not a file."),
+	}
+	// Relative paths in findings are relative to the working directory
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(root); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	out, err := reportSARIF(items, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var log sarifLog
+	if err := json.Unmarshal([]byte(out), &log); err != nil {
+		t.Fatal(err)
+	}
+	if log.Version != sarifVersion || len(log.Runs) != 1 {
+		t.Fatalf("got version %q with %d runs", log.Version, len(log.Runs))
+	}
+	run := log.Runs[0]
+	if got := run.OriginalURIBaseIDs[sarifRootID].URI; got != fileURI(root) {
+		t.Errorf("%s = %q, want %q", sarifRootID, got, fileURI(root))
+	}
+
+	type result struct {
+		rule, level, uri string
+		region           *sarifRegion
+	}
+	want := []result{
+		{findingRuleID, levelError, "main.go", &sarifRegion{StartLine: 3, EndLine: 5, StartColumn: 2}},
+		{"style", levelNote, "util.go", nil},
+		{findingRuleID, levelWarning, "", nil},
+		{reviewRuleID, levelNote, "util.go", nil},
+		{reviewRuleID, levelNote, "", nil},
+	}
+	if len(run.Results) != len(want) {
+		t.Fatalf("got %d results, want %d", len(run.Results), len(want))
+	}
+	for i, w := range want {
+		r := run.Results[i]
+		got := result{rule: r.RuleID, level: r.Level}
+		if len(r.Locations) != 0 {
+			location := r.Locations[0].PhysicalLocation
+			got.uri, got.region = location.ArtifactLocation.URI, location.Region
+			if location.ArtifactLocation.URIBaseID != sarifRootID {
+				t.Errorf("result %d is not relative to %s", i, sarifRootID)
+			}
+		}
+		if !reflect.DeepEqual(got, w) {
+			t.Errorf("result %d = %+v, want %+v", i, got, w)
+		}
+	}
+	if got := run.Results[4].Message.Text; got != "Not a file." {
+		t.Errorf("review without findings has message %q", got)
+	}
+
+	var rules []string
+	for _, rule := range run.Tool.Driver.Rules {
+		rules = append(rules, rule.ID)
+	}
+	if want := []string{findingRuleID, "style", reviewRuleID}; !reflect.DeepEqual(rules, want) {
+		t.Errorf("rules = %v, want %v", rules, want)
+	}
+}
+
+func TestFinishedReview(t *testing.T) {
+	finished := reviewVersion{ReviewedAt: time.Unix(1, 0), Model: "m", PayloadHash: "h", Review: "ok"}
+	failed := reviewVersion{ReviewedAt: time.Unix(2, 0), Model: "m", Review: "Failed to get review", State: reviewFailed}
+
+	review, ok := finishedReview(reviewInfo{ID: "1"}.addVersion(finished).addVersion(failed))
+	if !ok || review.Review != "ok" || review.State != reviewFinished || len(review.History) != 1 {
+		t.Errorf("finishedReview() = %q, %q, %d versions, %v, want the finished version", review.Review, review.State, len(review.History), ok)
+	}
+	if _, ok := finishedReview(reviewInfo{ID: "2"}.addVersion(failed)); ok {
+		t.Errorf("a review that never finished is reported")
+	}
+	if items := reportFindings([]reportItem{{review: reviewInfo{ID: "2"}.addVersion(failed)}}, t.TempDir()); len(items) != 0 {
+		t.Errorf("got %d findings of a failed review", len(items))
+	}
+}
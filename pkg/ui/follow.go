@@ -0,0 +1,232 @@
+package ui
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/shutils/lazyreview/pkg/config"
+)
+
+const (
+	defaultFollowLines = 1000
+	// Longer lines, and output without newlines, are split into lines of this many bytes
+	followLineBytes    = 16 << 10
+	followTickInterval = 200 * time.Millisecond
+	// Time a follow previewer runs to take a snapshot of an item that is not being followed
+	followSnapshotDuration = 2 * time.Second
+)
+
+// followTickMsg asks to refresh the Content panel with the output of the followed previewer.
+type followTickMsg struct {
+	seq int
+}
+
+// ringBuffer keeps the last lines written to it, holding at most its size times followLineBytes bytes.
+// It is written by the previewer process and read by Update.
+type ringBuffer struct {
+	mu      sync.Mutex
+	lines   []string
+	start   int
+	count   int
+	partial string
+	version int
+}
+
+func newRingBuffer(size int) *ringBuffer {
+	return &ringBuffer{lines: make([]string, size)}
+}
+
+func (b *ringBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	text := b.partial + string(p)
+	lines := strings.Split(text, "\n")
+	partial := lines[len(lines)-1]
+	for _, line := range lines[:len(lines)-1] {
+		for len(line) > followLineBytes {
+			var head string
+			head, line = splitLine(line)
+			b.push(head)
+		}
+		b.push(line)
+	}
+	for len(partial) > followLineBytes {
+		var head string
+		head, partial = splitLine(partial)
+		b.push(head)
+	}
+	b.partial = partial
+	b.version++
+	return len(p), nil
+}
+
+// splitLine splits a line longer than followLineBytes at a rune boundary.
+func splitLine(line string) (string, string) {
+	i := followLineBytes
+	for i > 0 && !utf8.RuneStart(line[i]) {
+		i--
+	}
+	if i == 0 {
+		i = followLineBytes
+	}
+	return line[:i], line[i:]
+}
+
+func (b *ringBuffer) push(line string) {
+	if b.count < len(b.lines) {
+		b.lines[(b.start+b.count)%len(b.lines)] = line
+		b.count++
+		return
+	}
+	b.lines[b.start] = line
+	b.start = (b.start + 1) % len(b.lines)
+}
+
+// snapshot returns the buffered lines and the number of writes made so far.
+func (b *ringBuffer) snapshot() (string, int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	lines := make([]string, 0, b.count+1)
+	for i := 0; i < b.count; i++ {
+		lines = append(lines, b.lines[(b.start+i)%len(b.lines)])
+	}
+	if b.partial != "" {
+		lines = append(lines, b.partial)
+	}
+	return strings.Join(lines, "\n"), b.version
+}
+
+// followProcess is a previewer kept running for the selected item of a follow source.
+type followProcess struct {
+	id      string
+	seq     int
+	buffer  *ringBuffer
+	cancel  context.CancelFunc
+	done    chan struct{}
+	result  commandResult
+	version int // Version of the buffer shown in the Content panel
+}
+
+func (p *followProcess) isDone() bool {
+	select {
+	case <-p.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func followLines(source config.Source) int {
+	if source.FollowLines > 0 {
+		return source.FollowLines
+	}
+	return defaultFollowLines
+}
+
+// startFollowProcess runs the previewer of a follow source for item, streaming its
+// stdout and stderr into a ring buffer until ctx is done or the process exits.
+func startFollowProcess(ctx context.Context, item listItem, source config.Source, conf config.Config, log *commandLog) *followProcess {
+	ctx, cancel := context.WithCancel(ctx)
+	p := &followProcess{
+		id:     item.id,
+		buffer: newRingBuffer(followLines(source)),
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	vars := itemCommandVars(item, sourceTarget(conf, source))
+	args, used := vars.expand(source.Previewer)
+	if !used {
+		args = append(args, item.param)
+	}
+	go func() {
+		defer close(p.done)
+		p.result = streamCommand(ctx, "previewer", item.sourceName, conf.WorkDir, args, vars, p.buffer)
+		if ctx.Err() == nil {
+			log.add(p.result)
+		}
+	}()
+	return p
+}
+
+// followSnapshot runs the previewer of a follow source for a short time and returns
+// the last lines of its output. It is used for items which are not being followed.
+func followSnapshot(ctx context.Context, item listItem, source config.Source, conf config.Config, log *commandLog) string {
+	ctx, cancel := context.WithTimeout(ctx, followSnapshotDuration)
+	defer cancel()
+	p := startFollowProcess(ctx, item, source, conf, log)
+	<-p.done
+	content, _ := p.buffer.snapshot()
+	return content
+}
+
+func followTick(seq int) tea.Cmd {
+	return tea.Tick(followTickInterval, func(time.Time) tea.Msg {
+		return followTickMsg{seq: seq}
+	})
+}
+
+// startFollowing starts following the selected item if its source has follow enabled,
+// and stops following any other item. An item already being followed keeps its process.
+func (m *model) startFollowing(item listItem) (tea.Cmd, bool) {
+	source, err := getSource(item.sourceName, m.conf.Sources)
+	if item.sourceName == "" || err != nil || m.showPayload || !source.Follow || len(source.Previewer) == 0 {
+		m.stopFollowing()
+		return nil, false
+	}
+	if m.isFollowing(item.id) {
+		return nil, true
+	}
+	m.stopFollowing()
+	m.follow = startFollowProcess(context.Background(), item, source, m.conf, m.commandLog)
+	m.follow.seq = m.previewSeq
+	m.loadContentPanel("")
+	return followTick(m.follow.seq), true
+}
+
+func (m *model) stopFollowing() {
+	if m.follow != nil {
+		m.follow.cancel()
+		m.follow = nil
+	}
+}
+
+// isFollowing reports whether the previewer of the item is being followed.
+func (m *model) isFollowing(id string) bool {
+	return m.follow != nil && m.follow.id == id
+}
+
+// handleFollowTick shows the new output of the followed previewer, keeping the
+// Content panel scrolled to the bottom unless the user scrolled up.
+func (m *model) handleFollowTick(msg followTickMsg) tea.Cmd {
+	if m.follow == nil || m.follow.seq != msg.seq {
+		return nil
+	}
+	done := m.follow.isDone()
+	content, version := m.follow.buffer.snapshot()
+	if version != m.follow.version || done {
+		if done {
+			content += "\n\n" + followExitText(m.follow.result)
+		}
+		atBottom := m.panels.itemPreviewPanel.AtBottom()
+		m.panels.itemPreviewPanel.SetContent(content)
+		if atBottom {
+			m.panels.itemPreviewPanel.GotoBottom()
+		}
+		m.follow.version = version
+	}
+	if done {
+		return nil
+	}
+	return followTick(msg.seq)
+}
+
+func followExitText(result commandResult) string {
+	if result.failed() {
+		return "[previewer exited: " + result.err.Error() + "]"
+	}
+	return "[previewer exited]"
+}
@@ -0,0 +1,57 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestRingBuffer(t *testing.T) {
+	b := newRingBuffer(3)
+	for _, write := range []string{"one\ntw", "o\nthree\n", "four\nfi", "ve"} {
+		if n, err := b.Write([]byte(write)); n != len(write) || err != nil {
+			t.Fatalf("Write(%q) = %d, %v", write, n, err)
+		}
+	}
+	text, version := b.snapshot()
+	if want := "two\nthree\nfour\nfive"; text != want {
+		t.Errorf("snapshot() = %q, want %q", text, want)
+	}
+	if version != 4 {
+		t.Errorf("version = %d, want 4", version)
+	}
+
+	b.Write([]byte("\n"))
+	if text, _ := b.snapshot(); text != "three\nfour\nfive" {
+		t.Errorf("snapshot() after the partial line ended = %q", text)
+	}
+}
+
+func TestRingBufferSplitsLongLines(t *testing.T) {
+	b := newRingBuffer(10)
+	// A multibyte rune straddles the split point of the first line
+	long := strings.Repeat("a", followLineBytes-1) + "é" + strings.Repeat("b", 10)
+	b.Write([]byte(long + "\n"))
+	// Output without newlines is split as it is written
+	b.Write([]byte(strings.Repeat("c", followLineBytes+5)))
+
+	text, _ := b.snapshot()
+	lines := strings.Split(text, "\n")
+	want := []string{
+		strings.Repeat("a", followLineBytes-1),
+		"é" + strings.Repeat("b", 10),
+		strings.Repeat("c", followLineBytes),
+		"ccccc",
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("got %d lines, want %d", len(lines), len(want))
+	}
+	for i := range want {
+		if lines[i] != want[i] {
+			t.Errorf("line %d has %d bytes, want %d", i, len(lines[i]), len(want[i]))
+		}
+		if !utf8.ValidString(lines[i]) {
+			t.Errorf("line %d splits a rune", i)
+		}
+	}
+}
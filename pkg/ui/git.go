@@ -0,0 +1,55 @@
+package ui
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"os/user"
+	"strings"
+)
+
+// git runs git in dir and returns its trimmed output.
+func git(dir string, args ...string) (string, error) {
+	return gitWithInput(dir, "", args...)
+}
+
+// gitWithInput runs git in dir with input on stdin and returns its trimmed output.
+func gitWithInput(dir string, input string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Stdin = strings.NewReader(input)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s: %s", strings.Join(args, " "), msg)
+		}
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// gitBranch returns the branch checked out in dir, or "" if there is none.
+func gitBranch(dir string) string {
+	branch, err := git(dir, "rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil || branch == "HEAD" {
+		return ""
+	}
+	return branch
+}
+
+// reviewAuthor returns the name recorded as the author of reviews made here:
+// the configured author, the git user of the project, or the OS user.
+func reviewAuthor(configured string, root string) string {
+	if configured != "" {
+		return configured
+	}
+	if name, err := git(root, "config", "user.name"); err == nil && name != "" {
+		return name
+	}
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return ""
+}
@@ -0,0 +1,87 @@
+package ui
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/formatters"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+)
+
+const (
+	defaultPreviewTheme = "monokai"
+	// Disables syntax highlighting while keeping the line numbers
+	noPreviewTheme = "none"
+)
+
+// highlightText renders text with syntax highlighting and a gutter of the line numbers given by numbering.
+// The language is detected from the file name, then from the content.
+func highlightText(fileName string, text string, theme string, numbering lineNumbering) string {
+	if theme == "" {
+		theme = defaultPreviewTheme
+	}
+	if theme == noPreviewTheme {
+		return numberLines(text, numbering)
+	}
+
+	lexer := lexers.Match(filepath.Base(fileName))
+	if lexer == nil {
+		lexer = lexers.Analyse(text)
+	}
+	if lexer == nil {
+		lexer = lexers.Fallback
+	}
+	lexer = chroma.Coalesce(lexer)
+
+	tokens, err := chroma.Tokenise(lexer, nil, text)
+	if err != nil {
+		return numberLines(text, numbering)
+	}
+
+	style := styles.Get(theme)
+	lines := chroma.SplitTokensIntoLines(tokens)
+	width := gutterWidth(len(lines), numbering)
+	var sb strings.Builder
+	for i, line := range lines {
+		for j := range line {
+			line[j].Value = strings.TrimSuffix(line[j].Value, "\n")
+		}
+		var formatted strings.Builder
+		if err := formatters.TTY256.Format(&formatted, style, chroma.Literator(line...)); err != nil {
+			return numberLines(text, numbering)
+		}
+		sb.WriteString(gutter(numbering, i, width))
+		sb.WriteString(formatted.String())
+		sb.WriteString("\n")
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// numberLines prefixes every line of text with its line number given by numbering.
+func numberLines(text string, numbering lineNumbering) string {
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	width := gutterWidth(len(lines), numbering)
+	for i, line := range lines {
+		lines[i] = gutter(numbering, i, width) + line
+	}
+	return strings.Join(lines, "\n")
+}
+
+// gutterWidth returns the width of the largest line number of a text of count lines.
+func gutterWidth(count int, numbering lineNumbering) int {
+	last, _ := numbering.number(count - 1)
+	return len(fmt.Sprint(last))
+}
+
+// gutter returns the gutter of the line at index i. The omission marker of a truncated
+// file has no line number.
+func gutter(numbering lineNumbering, i int, width int) string {
+	number, ok := numbering.number(i)
+	if !ok {
+		return fmt.Sprintf("%*s │ ", width, "")
+	}
+	return fmt.Sprintf("%*d │ ", width, number)
+}
@@ -0,0 +1,272 @@
+package ui
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/shutils/lazyreview/pkg/state"
+)
+
+// Word diffs larger than this many token pairs are shown as a whole replacement
+const maxDiffCells = 4_000_000
+
+var (
+	diffInsertStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("2"))
+	diffDeleteStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("1")).Strikethrough(true)
+	diffTokenRegexp = regexp.MustCompile(`\s+|[^\s]+`)
+)
+
+// States of the latest review of an item, stored with the review
+const (
+	reviewFinished = "finish"
+	reviewFailed   = "failed"
+)
+
+// reviewVersion is one review of an item. Versions are only ever appended.
+type reviewVersion struct {
+	ReviewedAt  time.Time   `json:"reviewedAt"`
+	Model       string      `json:"model,omitempty"`
+	Prompt      string      `json:"prompt,omitempty"`
+	ContextIDs  []string    `json:"contextIds,omitempty"`
+	PayloadHash string      `json:"payloadHash,omitempty"`
+	Usage       state.Usage `json:"usage"`
+	Review      string      `json:"review"`
+	Author      string      `json:"author,omitempty"` // Who requested the review
+	State       string      `json:"state,omitempty"`  // reviewFailed for a failed request, empty when finished
+}
+
+// state returns the state of a review whose latest version is v. Versions made before
+// the state was stored are finished.
+func (v reviewVersion) state() string {
+	if v.State == "" {
+		return reviewFinished
+	}
+	return v.State
+}
+
+// versions returns the review history from oldest to newest.
+// Reviews saved before the history was kept have their latest review as the only version.
+func (r reviewInfo) versions() []reviewVersion {
+	if len(r.History) != 0 {
+		return r.History
+	}
+	if r.Review == "" {
+		return nil
+	}
+	return []reviewVersion{{
+		ReviewedAt:  r.ReviewedAt,
+		PayloadHash: r.PayloadHash,
+		Review:      r.Review,
+	}}
+}
+
+// addVersion appends version to the history and makes it the latest review.
+func (r reviewInfo) addVersion(version reviewVersion) reviewInfo {
+	r.History = append(r.versions(), version)
+	r.Review = version.Review
+	r.ReviewedAt = version.ReviewedAt
+	r.PayloadHash = version.PayloadHash
+	r.State = version.state()
+	return r
+}
+
+// reviewHistoryView is the version of the review history shown in the Review panel.
+type reviewHistoryView struct {
+	id     string
+	offset int // Number of versions back from the latest one
+	diff   bool
+}
+
+// shownReview returns the review text of the item in the Review panel and whether it
+// is already formatted, which is the case for diffs.
+func (m *model) shownReview(id string) (string, bool) {
+	if m.reviewHistory.id != id {
+		m.reviewHistory = reviewHistoryView{id: id}
+	}
+	index := m.getReviewIndex(id)
+	if index == -1 {
+		return "", false
+	}
+	versions := m.reviewList[index].versions()
+	if len(versions) == 0 {
+		return "", false
+	}
+	if m.reviewHistory.offset >= len(versions) {
+		m.reviewHistory.offset = len(versions) - 1
+	}
+	current := len(versions) - 1 - m.reviewHistory.offset
+	version := versions[current]
+	if m.reviewHistory.diff && current > 0 {
+		header := fmt.Sprintf("Diff of version %d against version %d\n\n", current+1, current)
+		return header + wordDiff(versions[current-1].Review, version.Review), true
+	}
+	if len(versions) == 1 {
+		return version.Review, false
+	}
+	return versionHeader(version, current+1, len(versions)) + version.Review, false
+}
+
+func versionHeader(version reviewVersion, number int, total int) string {
+	fields := []string{fmt.Sprintf("Version %d/%d", number, total)}
+	if version.Author != "" {
+		fields = append(fields, "by "+version.Author)
+	}
+	if !version.ReviewedAt.IsZero() {
+		fields = append(fields, version.ReviewedAt.Local().Format("2006-01-02 15:04"))
+	}
+	if version.Model != "" {
+		fields = append(fields, version.Model)
+	}
+	if tokens := version.Usage.PromptTokens + version.Usage.CompletionTokens; tokens != 0 {
+		fields = append(fields, fmt.Sprintf("%d tokens", tokens))
+	}
+	if len(version.ContextIDs) != 0 {
+		fields = append(fields, fmt.Sprintf("%d context items", len(version.ContextIDs)))
+	}
+	return "_" + strings.Join(fields, " · ") + "_\n\n"
+}
+
+// reviewPanelTitle shows which version of the review is displayed, and who made it
+// if it was imported from someone else.
+func (m *model) reviewPanelTitle() string {
+	selectedItem, ok := m.panels.itemListPanel.model.SelectedItem().(listItem)
+	if !ok {
+		return "Review"
+	}
+	index := m.getReviewIndex(selectedItem.id)
+	if index == -1 {
+		return "Review"
+	}
+	versions := m.reviewList[index].versions()
+	total := len(versions)
+	offset := 0
+	if m.reviewHistory.id == selectedItem.id {
+		offset = min(m.reviewHistory.offset, total-1)
+	}
+	title := "Review"
+	current := total - offset
+	if total > 1 && m.reviewHistory.id == selectedItem.id {
+		if m.reviewHistory.diff && current > 1 {
+			title = fmt.Sprintf("Review (diff v%d→v%d)", current-1, current)
+		} else {
+			title = fmt.Sprintf("Review (v%d/%d)", current, total)
+		}
+	}
+	if total > 0 {
+		if author := versions[current-1].Author; author != "" && author != m.author {
+			title += " by " + author
+		}
+	}
+	return title
+}
+
+func (m *model) PreviousReviewVersion() (tea.Model, tea.Cmd) {
+	m.reviewHistory.offset++
+	return m.onChangeListSelectedItem()
+}
+
+func (m *model) NextReviewVersion() (tea.Model, tea.Cmd) {
+	if m.reviewHistory.offset > 0 {
+		m.reviewHistory.offset--
+	}
+	return m.onChangeListSelectedItem()
+}
+
+func (m *model) ToggleReviewDiff() (tea.Model, tea.Cmd) {
+	m.reviewHistory.diff = !m.reviewHistory.diff
+	return m.onChangeListSelectedItem()
+}
+
+// wordDiff marks the words deleted from old and inserted into new.
+func wordDiff(old string, new string) string {
+	a := diffTokenRegexp.FindAllString(old, -1)
+	b := diffTokenRegexp.FindAllString(new, -1)
+
+	// Common prefix and suffix are kept out of the LCS table
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var sb strings.Builder
+	sb.WriteString(strings.Join(a[:prefix], ""))
+	writeDiff(&sb, a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])
+	sb.WriteString(strings.Join(a[len(a)-suffix:], ""))
+	return sb.String()
+}
+
+func writeDiff(sb *strings.Builder, a []string, b []string) {
+	if len(a)*len(b) > maxDiffCells {
+		writeDeleted(sb, a)
+		writeInserted(sb, b)
+		return
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int32, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int32, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var deleted, inserted []string
+	flush := func() {
+		writeDeleted(sb, deleted)
+		writeInserted(sb, inserted)
+		deleted, inserted = nil, nil
+	}
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			flush()
+			sb.WriteString(a[i])
+			i++
+			j++
+		case j == len(b) || i < len(a) && lcs[i+1][j] >= lcs[i][j+1]:
+			deleted = append(deleted, a[i])
+			i++
+		default:
+			inserted = append(inserted, b[j])
+			j++
+		}
+	}
+	flush()
+}
+
+func writeDeleted(sb *strings.Builder, tokens []string) {
+	writeStyled(sb, diffDeleteStyle, tokens)
+}
+
+func writeInserted(sb *strings.Builder, tokens []string) {
+	writeStyled(sb, diffInsertStyle, tokens)
+}
+
+// writeStyled styles every line separately, as lipgloss pads multiline text to a block.
+func writeStyled(sb *strings.Builder, style lipgloss.Style, tokens []string) {
+	lines := strings.Split(strings.Join(tokens, ""), "\n")
+	for i, line := range lines {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		if line != "" {
+			sb.WriteString(style.Render(line))
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// markDiffs makes word diffs render as [-deleted-] and {+inserted+} for the duration of the test.
+func markDiffs(t *testing.T) {
+	insert, remove := diffInsertStyle, diffDeleteStyle
+	t.Cleanup(func() {
+		diffInsertStyle, diffDeleteStyle = insert, remove
+	})
+	diffInsertStyle = lipgloss.NewStyle().Transform(func(s string) string { return "{+" + s + "+}" })
+	diffDeleteStyle = lipgloss.NewStyle().Transform(func(s string) string { return "[-" + s + "-]" })
+}
+
+func TestWordDiff(t *testing.T) {
+	markDiffs(t)
+	tests := []struct {
+		name string
+		old  string
+		new  string
+		want string
+	}{
+		{"equal", "the same text", "the same text", "the same text"},
+		{"empty", "", "", ""},
+		{"inserted", "", "new text", "{+new text+}"},
+		{"deleted", "old text", "", "[-old text-]"},
+		{"replaced word", "use a map here", "use a slice here", "use a [-map-]{+slice+} here"},
+		{"inserted word", "check the error", "check the returned error", "check the {+returned +}error"},
+		{"deleted word", "a very long line", "a long line", "a [-very -]long line"},
+		{"whitespace", "a b", "a  b", "a[- -]{+  +}b"},
+		{"multiline", "one\ntwo\nthree", "one\n2\nthree", "one\n[-two-]{+2+}\nthree"},
+		{"lines inserted", "a\nb", "a\nx\ny\nb", "a\n{+x+}\n{+y+}\nb"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := wordDiff(tt.old, tt.new); got != tt.want {
+				t.Errorf("wordDiff(%q, %q) = %q, want %q", tt.old, tt.new, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWordDiffTooLarge(t *testing.T) {
+	markDiffs(t)
+	// More token pairs than maxDiffCells are shown as a whole replacement
+	old := strings.Repeat("a ", 1500) + "end"
+	new := strings.Repeat("b ", 1500) + "end"
+	want := "[-" + strings.Repeat("a ", 1499) + "a-]{+" + strings.Repeat("b ", 1499) + "b+} end"
+	if got := wordDiff(old, new); got != want {
+		t.Errorf("wordDiff() of large texts is not a whole replacement")
+	}
+}
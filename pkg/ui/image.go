@@ -0,0 +1,147 @@
+package ui
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/shutils/lazyreview/pkg/config"
+)
+
+// Images larger than this are not sent to the AI, as the API rejects them
+const maxImageSize = 20 << 20
+
+// Media types of the image formats accepted by vision-capable models
+var imageMediaTypes = map[string]string{
+	".png":  "image/png",
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".gif":  "image/gif",
+	".webp": "image/webp",
+}
+
+func isImageFile(path string) bool {
+	_, ok := imageMediaTypes[strings.ToLower(filepath.Ext(path))]
+	return ok
+}
+
+// imageConfig reads the format and dimensions of the image at path.
+func imageConfig(path string) (image.Config, string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return image.Config{}, "", err
+	}
+	defer file.Close()
+
+	header := make([]byte, 30)
+	n, err := io.ReadFull(file, header)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return image.Config{}, "", err
+	}
+	if cfg, ok := webpConfig(header[:n]); ok {
+		return cfg, "webp", nil
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return image.Config{}, "", err
+	}
+	return image.DecodeConfig(file)
+}
+
+// webpConfig reads the dimensions from the header of a WebP image.
+// The standard library has no WebP decoder, so the first chunk is parsed directly.
+func webpConfig(header []byte) (image.Config, bool) {
+	if len(header) < 30 || !bytes.Equal(header[0:4], []byte("RIFF")) || !bytes.Equal(header[8:12], []byte("WEBP")) {
+		return image.Config{}, false
+	}
+	uint24 := func(b []byte) int {
+		return int(b[0]) | int(b[1])<<8 | int(b[2])<<16
+	}
+	switch string(header[12:16]) {
+	case "VP8X":
+		return image.Config{Width: uint24(header[24:]) + 1, Height: uint24(header[27:]) + 1}, true
+	case "VP8 ":
+		width := binary.LittleEndian.Uint16(header[26:]) & 0x3fff
+		height := binary.LittleEndian.Uint16(header[28:]) & 0x3fff
+		return image.Config{Width: int(width), Height: int(height)}, true
+	case "VP8L":
+		bits := binary.LittleEndian.Uint32(header[21:])
+		return image.Config{Width: int(bits&0x3fff) + 1, Height: int(bits>>14&0x3fff) + 1}, true
+	}
+	return image.Config{}, false
+}
+
+// imageSummary describes the image at path in place of its content.
+func imageSummary(path string, conf config.Config) string {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Image\n\nPath: %s\n", path)
+	if cfg, format, err := imageConfig(path); err != nil {
+		fmt.Fprintf(&sb, "Format: unknown (%v)\n", err)
+	} else {
+		fmt.Fprintf(&sb, "Format: %s\nDimensions: %dx%d\n", format, cfg.Width, cfg.Height)
+	}
+	fmt.Fprintf(&sb, "Size: %s\nModified: %s\n\n", formatSize(info.Size()), info.ModTime().Format("2006-01-02 15:04:05"))
+
+	switch {
+	case !conf.Vision:
+		sb.WriteString("The image is not sent to the AI. Set vision = true if the model accepts images.")
+	case info.Size() > maxImageSize:
+		fmt.Fprintf(&sb, "The image is not sent to the AI because it is larger than %s.", formatSize(maxImageSize))
+	default:
+		sb.WriteString("The image is sent to the AI with the review request.")
+	}
+	return sb.String()
+}
+
+// imageDataURL encodes the image at path as a base64 data URL.
+func imageDataURL(path string) (string, error) {
+	mediaType, ok := imageMediaTypes[strings.ToLower(filepath.Ext(path))]
+	if !ok {
+		return "", fmt.Errorf("%s is not a supported image", path)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if info.Size() > maxImageSize {
+		return "", fmt.Errorf("%s is larger than %s", path, formatSize(maxImageSize))
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return "data:" + mediaType + ";base64," + base64.StdEncoding.EncodeToString(data), nil
+}
+
+// payloadImage returns the image sent to the AI for the item, if the item is an image
+// file shown by the default previewer and the model accepts images.
+func payloadImage(item listItem, conf config.Config) (string, bool) {
+	if !conf.Vision || !isImageFile(item.param) {
+		return "", false
+	}
+	if item.sourceName != "" {
+		source, _ := getSource(item.sourceName, conf.Sources)
+		if len(source.Payload) != 0 || len(source.Previewer) != 0 {
+			return "", false
+		}
+	}
+	url, err := imageDataURL(item.param)
+	if err != nil {
+		return "", false
+	}
+	return url, true
+}
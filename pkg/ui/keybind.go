@@ -18,6 +18,8 @@ type keyMaps struct {
 	stateKeyMap
 	contextKeyMap
 	sourceListKeyMap
+	commandLogKeyMap
+	projectListKeyMap
 	messageKeyMap
 }
 
@@ -33,6 +35,8 @@ func DefaultKeyMap() keyMaps {
 		stateKeyMap:         GetStateKeymap(),
 		contextKeyMap:       GetContextKeymap(),
 		sourceListKeyMap:    GetSourceListKeymap(),
+		commandLogKeyMap:    GetCommandLogKeymap(),
+		projectListKeyMap:   GetProjectListKeymap(),
 		messageKeyMap:       GetMessageKeymap(),
 	}
 }
@@ -92,6 +96,14 @@ func GetSourceListKeymap() sourceListKeyMap {
 	return SourceListKeyMap
 }
 
+func GetCommandLogKeymap() commandLogKeyMap {
+	return CommandLogKeyMap
+}
+
+func GetProjectListKeymap() projectListKeyMap {
+	return ProjectListKeyMap
+}
+
 func GetMessageKeymap() messageKeyMap {
 	return MessageKeyMap
 }
@@ -125,6 +137,10 @@ type listKeyMap struct {
 	ToggleAiContext           key.Binding
 	DeleteReviewResult        key.Binding
 	ToggleViewStyle           key.Binding
+	ReviewAllStale            key.Binding
+	CycleReviewFilter         key.Binding
+	FocusProjectListPanel     key.Binding
+	ExportReport              key.Binding
 }
 
 func (k listKeyMap) ShortHelp() []key.Binding {
@@ -142,6 +158,10 @@ func (k listKeyMap) ShortHelp() []key.Binding {
 		k.ToggleAiContext,
 		k.DeleteReviewResult,
 		k.ToggleViewStyle,
+		k.ReviewAllStale,
+		k.CycleReviewFilter,
+		k.FocusProjectListPanel,
+		k.ExportReport,
 		// k.ReviewContentCursorDown,
 		// k.ReviewContentCursorUp,
 		// k.ReviewContentHalfViewDown,
@@ -163,6 +183,10 @@ func (k listKeyMap) FullHelp() [][]key.Binding {
 			k.OpenReview,
 			k.DeleteReviewResult,
 			k.ToggleViewStyle,
+			k.ReviewAllStale,
+			k.CycleReviewFilter,
+			k.FocusProjectListPanel,
+			k.ExportReport,
 			// k.ReviewContentCursorDown,
 			// k.ReviewContentCursorUp,
 			// k.ReviewContentHalfViewDown,
@@ -240,6 +264,22 @@ var ListKeyMap = listKeyMap{
 		key.WithKeys("t"),
 		key.WithHelp("t", "toggle view style"),
 	),
+	ReviewAllStale: key.NewBinding(
+		key.WithKeys("R"),
+		key.WithHelp("R", "review stale"),
+	),
+	CycleReviewFilter: key.NewBinding(
+		key.WithKeys("f"),
+		key.WithHelp("f", "filter by review state"),
+	),
+	FocusProjectListPanel: key.NewBinding(
+		key.WithKeys("P"),
+		key.WithHelp("P", "switch project"),
+	),
+	ExportReport: key.NewBinding(
+		key.WithKeys("E"),
+		key.WithHelp("E", "export report"),
+	),
 }
 
 type contentKeyMap struct {
@@ -251,6 +291,7 @@ type contentKeyMap struct {
 	FocusInstantPrompt      key.Binding
 	FocusReviewPanel        key.Binding
 	FocusListPanel          key.Binding
+	TogglePayload           key.Binding
 }
 
 func (k contentKeyMap) ShortHelp() []key.Binding {
@@ -263,6 +304,7 @@ func (k contentKeyMap) ShortHelp() []key.Binding {
 		k.FocusInstantPrompt,
 		k.FocusReviewPanel,
 		k.FocusListPanel,
+		k.TogglePayload,
 	}
 }
 
@@ -277,6 +319,7 @@ func (k contentKeyMap) FullHelp() [][]key.Binding {
 			k.FocusInstantPrompt,
 			k.FocusReviewPanel,
 			k.FocusListPanel,
+			k.TogglePayload,
 		},
 	}
 }
@@ -314,6 +357,10 @@ var ContentKeyMap = contentKeyMap{
 		key.WithKeys("esc"),
 		key.WithHelp("esc", "focus list"),
 	),
+	TogglePayload: key.NewBinding(
+		key.WithKeys("p"),
+		key.WithHelp("p", "toggle payload"),
+	),
 }
 
 type reviewKeyMap struct {
@@ -325,6 +372,9 @@ type reviewKeyMap struct {
 	FocusInstantPrompt        key.Binding
 	FocusContentPanel         key.Binding
 	FocusListPanel            key.Binding
+	PreviousVersion           key.Binding
+	NextVersion               key.Binding
+	ToggleDiff                key.Binding
 }
 
 func (k reviewKeyMap) ShortHelp() []key.Binding {
@@ -337,6 +387,9 @@ func (k reviewKeyMap) ShortHelp() []key.Binding {
 		k.FocusInstantPrompt,
 		k.FocusContentPanel,
 		k.FocusListPanel,
+		k.PreviousVersion,
+		k.NextVersion,
+		k.ToggleDiff,
 	}
 }
 
@@ -351,6 +404,9 @@ func (k reviewKeyMap) FullHelp() [][]key.Binding {
 			k.FocusInstantPrompt,
 			k.FocusContentPanel,
 			k.FocusListPanel,
+			k.PreviousVersion,
+			k.NextVersion,
+			k.ToggleDiff,
 		},
 	}
 }
@@ -388,6 +444,18 @@ var ReviewKeyMap = reviewKeyMap{
 		key.WithKeys("esc"),
 		key.WithHelp("esc", "focus list"),
 	),
+	PreviousVersion: key.NewBinding(
+		key.WithKeys("["),
+		key.WithHelp("[", "previous version"),
+	),
+	NextVersion: key.NewBinding(
+		key.WithKeys("]"),
+		key.WithHelp("]", "next version"),
+	),
+	ToggleDiff: key.NewBinding(
+		key.WithKeys("D"),
+		key.WithHelp("D", "toggle diff"),
+	),
 }
 
 type reviewStackKeyMap struct {
@@ -580,9 +648,10 @@ var ContextKeyMap = contextKeyMap{
 }
 
 type sourceListKeyMap struct {
-	FocusContextPanel   key.Binding
-	FocusConfigPanel    key.Binding
-	ToggleSourceEnabled key.Binding
+	FocusContextPanel    key.Binding
+	FocusConfigPanel     key.Binding
+	ToggleSourceEnabled  key.Binding
+	FocusCommandLogPanel key.Binding
 }
 
 func (k sourceListKeyMap) ShortHelp() []key.Binding {
@@ -590,6 +659,7 @@ func (k sourceListKeyMap) ShortHelp() []key.Binding {
 		k.FocusContextPanel,
 		k.FocusConfigPanel,
 		k.ToggleSourceEnabled,
+		k.FocusCommandLogPanel,
 	}
 }
 
@@ -599,6 +669,7 @@ func (k sourceListKeyMap) FullHelp() [][]key.Binding {
 			k.FocusContextPanel,
 			k.FocusConfigPanel,
 			k.ToggleSourceEnabled,
+			k.FocusCommandLogPanel,
 		},
 	}
 }
@@ -616,6 +687,95 @@ var SourceListKeyMap = sourceListKeyMap{
 		key.WithKeys(" "),
 		key.WithHelp("space", "toggle source enabled"),
 	),
+	FocusCommandLogPanel: key.NewBinding(
+		key.WithKeys("e"),
+		key.WithHelp("e", "command log"),
+	),
+}
+
+type commandLogKeyMap struct {
+	FocusSourceListPanel key.Binding
+	CursorDown           key.Binding
+	CursorUp             key.Binding
+}
+
+func (k commandLogKeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{
+		k.FocusSourceListPanel,
+		k.CursorDown,
+		k.CursorUp,
+	}
+}
+
+func (k commandLogKeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{
+		{
+			k.FocusSourceListPanel,
+			k.CursorDown,
+			k.CursorUp,
+		},
+	}
+}
+
+var CommandLogKeyMap = commandLogKeyMap{
+	FocusSourceListPanel: key.NewBinding(
+		key.WithKeys("esc"),
+		key.WithHelp("esc", "focus source list"),
+	),
+	CursorDown: key.NewBinding(
+		key.WithKeys("j", "down"),
+		key.WithHelp("j/↓", "down"),
+	),
+	CursorUp: key.NewBinding(
+		key.WithKeys("k", "up"),
+		key.WithHelp("k/↑", "up"),
+	),
+}
+
+type projectListKeyMap struct {
+	FocusItemListPanel key.Binding
+	CursorDown         key.Binding
+	CursorUp           key.Binding
+	SwitchProject      key.Binding
+}
+
+func (k projectListKeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{
+		k.FocusItemListPanel,
+		k.CursorDown,
+		k.CursorUp,
+		k.SwitchProject,
+	}
+}
+
+func (k projectListKeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{
+		{
+			k.FocusItemListPanel,
+			k.CursorDown,
+			k.CursorUp,
+			k.SwitchProject,
+		},
+	}
+}
+
+var ProjectListKeyMap = projectListKeyMap{
+	FocusItemListPanel: key.NewBinding(
+		key.WithKeys("esc"),
+		key.WithHelp("esc", "focus list"),
+	),
+	CursorDown: key.NewBinding(
+		key.WithKeys("j", "down"),
+		key.WithHelp("j/↓", "down"),
+	),
+	CursorUp: key.NewBinding(
+		key.WithKeys("k", "up"),
+		key.WithHelp("k/↑", "up"),
+	),
+	SwitchProject: key.NewBinding(
+		key.WithKeys("enter"),
+		key.WithHelp("enter", "switch project"),
+	),
 }
 
 type messageKeyMap struct {
@@ -706,6 +866,14 @@ func (m *model) handleItemListKey(msg tea.Msg) func() (tea.Model, tea.Cmd) {
 			return m.DeleteReviewResult
 		case key.Matches(msg, m.keyMaps.listKeyMap.ToggleViewStyle):
 			return m.ToggleItemListViewStyle
+		case key.Matches(msg, m.keyMaps.listKeyMap.ReviewAllStale):
+			return m.ReviewAllStale
+		case key.Matches(msg, m.keyMaps.listKeyMap.CycleReviewFilter):
+			return m.CycleReviewFilter
+		case key.Matches(msg, m.keyMaps.listKeyMap.FocusProjectListPanel):
+			return m.FocusProjectListPanel
+		case key.Matches(msg, m.keyMaps.listKeyMap.ExportReport):
+			return m.ExportReport
 		}
 	}
 	return nil
@@ -731,6 +899,8 @@ func (m *model) handleContentKey(msg tea.Msg) func() (tea.Model, tea.Cmd) {
 			return m.FocusReviewPanel
 		case key.Matches(msg, m.keyMaps.contentKeyMap.FocusListPanel):
 			return m.FocusItemListPanel
+		case key.Matches(msg, m.keyMaps.contentKeyMap.TogglePayload):
+			return m.TogglePayloadView
 		}
 	}
 	return nil
@@ -756,6 +926,12 @@ func (m *model) handleReviewKey(msg tea.Msg) func() (tea.Model, tea.Cmd) {
 			return m.FocusContentPanel
 		case key.Matches(msg, m.keyMaps.reviewKeyMap.FocusListPanel):
 			return m.FocusItemListPanel
+		case key.Matches(msg, m.keyMaps.reviewKeyMap.PreviousVersion):
+			return m.PreviousReviewVersion
+		case key.Matches(msg, m.keyMaps.reviewKeyMap.NextVersion):
+			return m.NextReviewVersion
+		case key.Matches(msg, m.keyMaps.reviewKeyMap.ToggleDiff):
+			return m.ToggleReviewDiff
 		}
 	}
 	return nil
@@ -816,6 +992,40 @@ func (m *model) handleSourceListKey(msg tea.Msg) func() (tea.Model, tea.Cmd) {
 			return m.FocusContextPanel
 		case key.Matches(msg, m.keyMaps.sourceListKeyMap.ToggleSourceEnabled):
 			return m.ToggleSourceEnabled
+		case key.Matches(msg, m.keyMaps.sourceListKeyMap.FocusCommandLogPanel):
+			return m.FocusCommandLogPanel
+		}
+	}
+	return nil
+}
+
+func (m *model) handleCommandLogKey(msg tea.Msg) func() (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, m.keyMaps.commandLogKeyMap.FocusSourceListPanel):
+			return m.FocusSourceListPanel
+		case key.Matches(msg, m.keyMaps.commandLogKeyMap.CursorDown):
+			return m.CommandLogCursorDown
+		case key.Matches(msg, m.keyMaps.commandLogKeyMap.CursorUp):
+			return m.CommandLogCursorUp
+		}
+	}
+	return nil
+}
+
+func (m *model) handleProjectListKey(msg tea.Msg) func() (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, m.keyMaps.projectListKeyMap.FocusItemListPanel):
+			return m.FocusItemListPanel
+		case key.Matches(msg, m.keyMaps.projectListKeyMap.CursorDown):
+			return m.ProjectListCursorDown
+		case key.Matches(msg, m.keyMaps.projectListKeyMap.CursorUp):
+			return m.ProjectListCursorUp
+		case key.Matches(msg, m.keyMaps.projectListKeyMap.SwitchProject):
+			return m.SwitchProject
 		}
 	}
 	return nil
@@ -937,6 +1147,18 @@ func (m *model) handleKey(msg tea.Msg) func() (tea.Model, tea.Cmd) {
 				return action()
 			}
 		}
+	case CommandLogPanelFocus:
+		if action := m.handleCommandLogKey(msg); action != nil {
+			return func() (tea.Model, tea.Cmd) {
+				return action()
+			}
+		}
+	case ProjectListPanelFocus:
+		if action := m.handleProjectListKey(msg); action != nil {
+			return func() (tea.Model, tea.Cmd) {
+				return action()
+			}
+		}
 	case MessagePanelFocus:
 		if action := m.handleMessageKey(msg); action != nil {
 			return func() (tea.Model, tea.Cmd) {
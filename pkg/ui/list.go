@@ -1,24 +1,144 @@
 package ui
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/list"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
 	"github.com/shutils/lazyreview/pkg/config"
 )
 
+const defaultCollectorTimeout = 30 * time.Second
+
+// Item title prefixes showing the review state
+const (
+	reviewedPrefix   = "☑ "
+	unreviewedPrefix = "☐ "
+	stalePrefix      = "⟳ "
+)
+
+type sourceCollectedMsg struct {
+	generation int
+	source     string
+	items      []list.Item
+	result     commandResult
+}
+
+const (
+	noReviewText = "No review"
+	loadingText  = "Loading..."
+)
+
+type previewMsg struct {
+	seq         int
+	entry       previewCacheEntry
+	showPayload bool
+	following   bool
+}
+
+// onChangeListSelectedItem shows the preview and review of the selected item.
+// Cached previews are shown immediately, others are loaded in the background
+// and any preview still loading for the previously selected item is cancelled.
+// Items of follow sources stream their previewer output instead.
 func (m *model) onChangeListSelectedItem() (*model, tea.Cmd) {
+	if m.previewCancel != nil {
+		m.previewCancel()
+		m.previewCancel = nil
+	}
+	m.previewSeq++
+
 	selectedItem, ok := m.panels.itemListPanel.model.SelectedItem().(listItem)
-	reviewContent := "No review"
-	itemContent := previewContent(selectedItem, m.conf.Sources)
-	if ok && m.getReviewIndex(selectedItem.id) != -1 {
-		reviewContent = getRendered(m.reviewList[m.getReviewIndex(selectedItem.id)].Review, m.conf.Glamour, m.panels.itemReviewPanel.Width)
+	if !ok {
+		m.stopFollowing()
+		m.loadReviewPanel(noReviewText)
+		m.loadContentPanel(defaultPreviewer("", m.conf))
+		return m, nil
+	}
+
+	review, reviewFormatted := m.shownReview(selectedItem.id)
+	width := m.panels.itemReviewPanel.Width
+
+	entry, _ := m.previewCache.get(selectedItem.id)
+	entry.id = selectedItem.id
+	entry.sourceName = selectedItem.sourceName
+	if review == "" {
+		entry.review = ""
+		entry.reviewWidth = width
+		entry.reviewFormatted = false
+		entry.renderedReview = noReviewText
+		entry.hasReview = true
+	}
+	reviewCached := entry.hasReview && entry.review == review && entry.reviewWidth == width && entry.reviewFormatted == reviewFormatted
+
+	if reviewCached {
+		m.loadReviewPanel(entry.renderedReview)
+	} else {
+		m.loadReviewPanel(loadingText)
+	}
+	showPayload := m.showPayload
+	contentCached := entry.hasContent
+	if showPayload {
+		contentCached = entry.hasPayload
+	}
+	followCmd, following := m.startFollowing(selectedItem)
+	if following {
+		contentCached = true
+	} else if contentCached {
+		m.loadContentPanel(entry.shownContent(showPayload))
+	} else {
+		m.loadContentPanel(loadingText)
+	}
+	if contentCached && reviewCached {
+		return m, followCmd
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.previewCancel = cancel
+	seq := m.previewSeq
+	conf := m.conf
+	glamourStyle := m.conf.Glamour
+	cmdLog := m.commandLog
+	return m, tea.Batch(followCmd, func() tea.Msg {
+		switch {
+		case following:
+			// The content is streamed by the follow process
+		case showPayload && !entry.hasPayload:
+			entry.payload = loadPayload(ctx, selectedItem, conf, cmdLog)
+			entry.hasPayload = true
+		case !showPayload && !entry.hasContent:
+			entry.content = loadPreview(ctx, selectedItem, conf, cmdLog)
+			entry.hasContent = true
+		}
+		if !reviewCached {
+			entry.review = review
+			entry.reviewWidth = width
+			entry.reviewFormatted = reviewFormatted
+			if reviewFormatted {
+				entry.renderedReview = lipgloss.NewStyle().Width(width).Render(review)
+			} else {
+				entry.renderedReview = getRendered(review, glamourStyle, width)
+			}
+			entry.hasReview = true
+		}
+		return previewMsg{seq: seq, entry: entry, showPayload: showPayload, following: following}
+	})
+}
+
+func (m *model) handlePreviewMsg(msg previewMsg) {
+	if msg.seq != m.previewSeq {
+		return
+	}
+	m.previewCancel = nil
+	m.previewCache.put(msg.entry)
+	m.loadReviewPanel(msg.entry.renderedReview)
+	if !msg.following {
+		m.loadContentPanel(msg.entry.shownContent(msg.showPayload))
 	}
-	m.loadReviewPanel(reviewContent)
-	m.loadContentPanel(itemContent)
-	return m, nil
 }
 
 func (m *model) loadReviewPanel(itemContent string) {
@@ -41,20 +161,15 @@ func findIndex(items []list.Item, id string) int {
 	return -1
 }
 
-func getItems(conf config.Config, reviewList []reviewInfo) []list.Item {
-	var items []list.Item
-
-	if len(conf.Sources) > 0 && !isDisabledAllSource(conf.Sources) {
-		items = collectItemsFromSources(conf.Sources, conf)
-	} else if len(conf.Collector) != 0 {
-		items = customCollector(conf.Collector, "")
-	} else {
-		items = defaultItemCollector(conf, "")
-	}
+// decorateItems assigns ids to collected items and prefixes their titles with the review state.
+// Items in stale, as found by checkStaleCmd, are marked stale.
+func decorateItems(collected []list.Item, reviewList []reviewInfo, stale map[string]bool, root string) []list.Item {
+	items := make([]list.Item, len(collected))
+	copy(items, collected)
 
-	reviewStateMap := make(map[string]string)
+	reviewMap := make(map[string]reviewInfo)
 	for _, review := range reviewList {
-		reviewStateMap[review.ID] = review.State
+		reviewMap[review.ID] = review
 	}
 
 	for i, item := range items {
@@ -64,22 +179,29 @@ func getItems(conf config.Config, reviewList []reviewInfo) []list.Item {
 		}
 
 		title := _item.Title()
-		id := makeHash(_item)
-		if state, exists := reviewStateMap[id]; exists {
-			if state == "finish" {
-				title = "☑ " + title
+		id := makeHash(root, _item)
+		if review, exists := reviewMap[id]; exists {
+			if review.State == reviewFinished && stale[id] {
+				title = stalePrefix + title
+			} else if review.State == reviewFinished {
+				title = reviewedPrefix + title
 			} else {
-				title = "☐ " + title
+				title = unreviewedPrefix + title
 			}
 		} else {
-			title = "☐ " + title
+			title = unreviewedPrefix + title
 		}
 
 		items[i] = listItem{
-			title:      title,
-			param:      _item.Description(),
-			sourceName: _item.sourceName,
-			id:         id,
+			title:       title,
+			param:       _item.param,
+			sourceName:  _item.sourceName,
+			id:          id,
+			key:         _item.key,
+			path:        _item.path,
+			description: _item.description,
+			group:       _item.group,
+			meta:        _item.meta,
 		}
 	}
 
@@ -110,7 +232,7 @@ func getItemListString(items []list.Item) string {
 	for _, item := range items {
 		_item, ok := item.(listItem)
 		if ok {
-			params = append(params, _item.Description())
+			params = append(params, _item.param)
 		}
 	}
 
@@ -126,24 +248,59 @@ func isDisabledAllSource(sources []config.Source) bool {
 	return true
 }
 
-func collectItemsFromSources(sources []config.Source, conf config.Config) []list.Item {
-	var items []list.Item
-
-	for _, source := range sources {
+// collectorSources returns the sources whose collectors build the item list.
+// If no source is enabled, the top-level collector is used as an unnamed source.
+func collectorSources(conf config.Config) []config.Source {
+	if len(conf.Sources) == 0 || isDisabledAllSource(conf.Sources) {
+		return []config.Source{{
+			Collector:     conf.Collector,
+			Watch:         conf.Watch,
+			WatchInterval: conf.WatchInterval,
+		}}
+	}
+	var sources []config.Source
+	for _, source := range conf.Sources {
 		if source.Enabled {
-			var collectedItems []list.Item
-			if len(source.Collector) == 0 {
-				collectedItems = defaultItemCollector(conf, source.Name)
-			} else {
-				collectedItems = customCollector(source.Collector, source.Name)
-			}
-			if len(collectedItems) != 0 {
-				items = append(items, collectedItems...)
-			}
+			sources = append(sources, source)
 		}
 	}
+	return sources
+}
+
+func runCollector(ctx context.Context, conf config.Config, source config.Source) ([]list.Item, commandResult) {
+	if len(source.Collector) == 0 {
+		return defaultItemCollector(ctx, conf, source)
+	}
+	vars := commandVars{
+		"source": source.Name,
+		"target": sourceTarget(conf, source),
+	}
+	return customCollector(ctx, source.Collector, source.Name, source.Format, conf.WorkDir, vars)
+}
 
-	return items
+// collectSourceCmd runs the collector of source in the background.
+func collectSourceCmd(ctx context.Context, conf config.Config, source config.Source, root string, generation int) tea.Cmd {
+	return func() tea.Msg {
+		timeout := defaultCollectorTimeout
+		if source.Timeout > 0 {
+			timeout = time.Duration(source.Timeout)
+		}
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		items, result := runCollector(ctx, conf, source)
+		resolveItemParams(items, conf.WorkDir)
+		setItemPaths(items, root)
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			result.err = fmt.Errorf("timed out after %s", timeout)
+		}
+		return sourceCollectedMsg{
+			generation: generation,
+			source:     source.Name,
+			items:      items,
+			result:     result,
+		}
+	}
 }
 
 func getSource(name string, sources []config.Source) (config.Source, error) {
@@ -155,24 +312,21 @@ func getSource(name string, sources []config.Source) (config.Source, error) {
 	return config.Source{}, fmt.Errorf("source with name '%s' not found", name)
 }
 
-func makeHash(item listItem) string {
-	seed := item.param + item.sourceName
-	return fmt.Sprintf("%x", seed)
-}
-
-func getSourceItems(sources []config.Source) []list.Item {
-	if len(sources) == 0 {
-		return []list.Item{}
+// getSourceItems returns the rows of the source list. The unnamed source of the top-level
+// collector is listed first while it runs, so that its errors are shown like those of named sources.
+func getSourceItems(conf config.Config, sourceErrors map[string]commandResult, loadingSources map[string]bool) []list.Item {
+	items := make([]list.Item, 0, len(conf.Sources)+1)
+	if len(conf.Sources) == 0 || isDisabledAllSource(conf.Sources) {
+		items = append(items, defaultSourceItem{source: collectorSources(conf)[0], loading: loadingSources[""]})
+		if result, ok := sourceErrors[""]; ok {
+			items = append(items, sourceErrorItem{result: result})
+		}
 	}
-	items := make([]list.Item, len(sources))
 
-	for i, source := range sources {
-		items[i] = config.Source{
-			Name:      source.Name,
-			Collector: source.Collector,
-			Previewer: source.Previewer,
-			Enabled:   source.Enabled,
-			Prompt:    source.Prompt,
+	for _, source := range conf.Sources {
+		items = append(items, sourceItem{source: source, loading: loadingSources[source.Name]})
+		if result, ok := sourceErrors[source.Name]; ok {
+			items = append(items, sourceErrorItem{result: result})
 		}
 	}
 
@@ -184,15 +338,162 @@ func (m *model) setSourceDetailContent() (tea.Model, tea.Cmd) {
 	if selectedSource == nil {
 		return m, nil
 	}
-	source, ok := selectedSource.(config.Source)
-	if !ok {
+	switch source := selectedSource.(type) {
+	case sourceItem:
+		m.panels.sourceDetailPanel.SetContent(source.source.String())
+	case defaultSourceItem:
+		m.panels.sourceDetailPanel.SetContent(source.source.String())
+	case sourceErrorItem:
+		m.panels.sourceDetailPanel.SetContent(source.result.String())
+	default:
 		return m, func() tea.Msg {
 			return showMessageMsg{
 				message: "Failed to set source details",
 			}
 		}
 	}
-	m.panels.sourceDetailPanel.SetContent(source.String())
 
 	return m, nil
 }
+
+// startCollecting cancels any running collection and starts the collectors of all active sources.
+// Items already collected for a source stay in the list until its collector finishes.
+func (m *model) startCollecting() tea.Cmd {
+	if m.collectCancel != nil {
+		m.collectCancel()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	m.collectCtx = ctx
+	m.collectCancel = cancel
+	m.collectGeneration++
+	m.sourceErrors = map[string]commandResult{}
+	m.loadingSources = map[string]bool{}
+	m.previewCache.clear()
+
+	var cmds []tea.Cmd
+	for _, source := range collectorSources(m.conf) {
+		m.loadingSources[source.Name] = true
+		cmds = append(cmds, collectSourceCmd(ctx, m.conf, source, m.projectRoot, m.collectGeneration))
+	}
+	m.refreshSourceList()
+	return tea.Batch(cmds...)
+}
+
+// collectSource re-runs the collector of a single source.
+func (m *model) collectSource(source config.Source) tea.Cmd {
+	m.loadingSources[source.Name] = true
+	m.refreshSourceList()
+	return collectSourceCmd(m.collectCtx, m.conf, source, m.projectRoot, m.collectGeneration)
+}
+
+// mergeCollectedItems replaces the items of the source in msg and rebuilds the item list.
+func (m *model) mergeCollectedItems(msg sourceCollectedMsg) tea.Cmd {
+	delete(m.loadingSources, msg.source)
+	m.commandLog.add(msg.result)
+	if msg.result.failed() {
+		m.sourceErrors[msg.source] = msg.result
+	}
+	m.collectedItems[msg.source] = msg.items
+	m.previewCache.removeSource(msg.source)
+	m.refreshSourceList()
+	var staleCmd, notesCmd tea.Cmd
+	if source, ok := m.findCollectorSource(msg.source); ok {
+		staleCmd = checkStaleCmd(m.collectCtx, m.conf, source, msg.items, m.reviewList, m.staleChecks, m.projectRoot, m.commandLog, m.collectGeneration)
+		notesCmd = loadNotesCmd(m.collectCtx, source, msg.items, m.projectRoot, m.collectGeneration)
+	}
+	return tea.Batch(m.rebuildItemList(), staleCmd, notesCmd)
+}
+
+// rebuildItemList sets the collected items of the active sources passing the review filter to the item list.
+func (m *model) rebuildItemList() tea.Cmd {
+	var items []list.Item
+	for _, source := range collectorSources(m.conf) {
+		items = append(items, m.collectedItems[source.Name]...)
+	}
+
+	prevSelected, _ := m.panels.itemListPanel.model.SelectedItem().(listItem)
+	cmd := m.panels.itemListPanel.model.SetItems(filterItems(decorateItems(items, m.reviewList, m.staleItems, m.projectRoot), m.reviewFilter))
+	if cmd != nil {
+		// The list is being refiltered. The cursor is restored once the matches arrive.
+		m.pendingSelectID = prevSelected.id
+		return cmd
+	}
+	return m.selectItemByID(prevSelected.id)
+}
+
+// selectItemByID moves the cursor to the visible item with id and refreshes the panels
+// if the selected item changed.
+func (m *model) selectItemByID(id string) tea.Cmd {
+	prevSelected, _ := m.panels.itemListPanel.model.SelectedItem().(listItem)
+	if index := findIndex(m.panels.itemListPanel.model.VisibleItems(), id); index != -1 {
+		m.panels.itemListPanel.model.Select(index)
+	}
+	selected, _ := m.panels.itemListPanel.model.SelectedItem().(listItem)
+	if selected.id != prevSelected.id || selected.id == id {
+		_, cmd := m.onChangeListSelectedItem()
+		return cmd
+	}
+	return nil
+}
+
+func (m *model) refreshSourceList() {
+	m.panels.sourceListPanel.SetItems(getSourceItems(m.conf, m.sourceErrors, m.loadingSources))
+	m.panels.commandLogPanel.SetContent(m.commandLog.String())
+}
+
+func (m *model) isCollecting() bool {
+	return len(m.loadingSources) > 0
+}
+
+// sourceItem is a row in the source list.
+type sourceItem struct {
+	source  config.Source
+	loading bool
+}
+
+func (i sourceItem) Title() string {
+	if i.loading {
+		return i.source.Title() + " (loading...)"
+	}
+	return i.source.Title()
+}
+func (i sourceItem) Description() string { return i.source.Description() }
+func (i sourceItem) FilterValue() string { return i.source.FilterValue() }
+
+// defaultSourceItem is the row of the unnamed source running the top-level collector,
+// which is used when no named source is enabled.
+type defaultSourceItem struct {
+	source  config.Source
+	loading bool
+}
+
+func (i defaultSourceItem) Title() string {
+	title := "● default"
+	if i.loading {
+		title += " (loading...)"
+	}
+	return title
+}
+func (i defaultSourceItem) Description() string {
+	if len(i.source.Collector) == 0 {
+		return "collector: built-in"
+	}
+	return "collector: " + strings.Join(i.source.Collector, ", ")
+}
+func (i defaultSourceItem) FilterValue() string { return "default" }
+
+// sourceErrorItem is a row in the source list describing a failed collector run.
+type sourceErrorItem struct {
+	result commandResult
+}
+
+func (i sourceErrorItem) Title() string {
+	return "  ✗ " + firstLine(i.result.err.Error())
+}
+func (i sourceErrorItem) Description() string { return i.result.stderr }
+func (i sourceErrorItem) FilterValue() string { return i.result.source }
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(s), "\n")
+	return line
+}
@@ -0,0 +1,166 @@
+package ui
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/shutils/lazyreview/pkg/config"
+)
+
+const (
+	// Limits of a text output kept in the rendered notebook
+	notebookOutputMaxLines = 20
+	notebookOutputMaxBytes = 2000
+	// Notebooks larger than this are shown as JSON, as their outputs make them slow to parse
+	notebookMaxSize = 32 << 20
+)
+
+// notebookText is a multiline string of a notebook, stored either as a string or as a list of lines.
+type notebookText string
+
+func (t *notebookText) UnmarshalJSON(data []byte) error {
+	var lines []string
+	if err := json.Unmarshal(data, &lines); err == nil {
+		*t = notebookText(strings.Join(lines, ""))
+		return nil
+	}
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return err
+	}
+	*t = notebookText(text)
+	return nil
+}
+
+type notebookOutput struct {
+	OutputType string                  `json:"output_type"`
+	Text       notebookText            `json:"text"`
+	Data       map[string]notebookText `json:"data"`
+	Ename      string                  `json:"ename"`
+	Evalue     string                  `json:"evalue"`
+}
+
+type notebookCell struct {
+	CellType       string           `json:"cell_type"`
+	Source         notebookText     `json:"source"`
+	ExecutionCount *int             `json:"execution_count"`
+	Outputs        []notebookOutput `json:"outputs"`
+}
+
+type notebook struct {
+	Cells    []notebookCell `json:"cells"`
+	Metadata struct {
+		Kernelspec struct {
+			Language string `json:"language"`
+		} `json:"kernelspec"`
+		LanguageInfo struct {
+			Name string `json:"name"`
+		} `json:"language_info"`
+	} `json:"metadata"`
+}
+
+func (nb notebook) language() string {
+	if nb.Metadata.LanguageInfo.Name != "" {
+		return nb.Metadata.LanguageInfo.Name
+	}
+	if nb.Metadata.Kernelspec.Language != "" {
+		return nb.Metadata.Kernelspec.Language
+	}
+	return "python"
+}
+
+func isNotebookFile(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".ipynb")
+}
+
+// renderNotebook renders the cells of the notebook at path in order as markdown,
+// numbering them so that reviews can refer to a cell. Outputs are left out unless
+// conf.NotebookOutputs is set, in which case their text is kept truncated.
+// The rendered notebook is truncated to maxSize bytes like other text files.
+func renderNotebook(path string, conf config.Config, maxSize int64) (string, lineNumbering, error) {
+	text, err := renderNotebookCells(path, conf)
+	if err != nil {
+		return "", lineNumbering{}, err
+	}
+	text, numbering := truncateText(text, maxSize)
+	return text, numbering, nil
+}
+
+func renderNotebookCells(path string, conf config.Config) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if info.Size() > notebookMaxSize {
+		return "", fmt.Errorf("notebook is larger than %s", formatSize(notebookMaxSize))
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	var nb notebook
+	if err := json.Unmarshal(data, &nb); err != nil {
+		return "", fmt.Errorf("failed to parse notebook: %w", err)
+	}
+
+	language := nb.language()
+	var cells []string
+	for i, cell := range nb.Cells {
+		var sb strings.Builder
+		source := strings.TrimRight(string(cell.Source), "\n")
+		switch cell.CellType {
+		case "code":
+			fmt.Fprintf(&sb, "## Cell %d (code", i+1)
+			if cell.ExecutionCount != nil {
+				fmt.Fprintf(&sb, ", In [%d]", *cell.ExecutionCount)
+			}
+			fence := codeFence(source)
+			fmt.Fprintf(&sb, ")\n\n%s%s\n%s\n%s", fence, language, source, fence)
+			if conf.NotebookOutputs {
+				for _, output := range cell.Outputs {
+					if text := output.text(); text != "" {
+						text = truncateOutput(text)
+						fence := codeFence(text)
+						fmt.Fprintf(&sb, "\n\nOutput:\n\n%stext\n%s\n%s", fence, text, fence)
+					}
+				}
+			}
+		default:
+			fmt.Fprintf(&sb, "## Cell %d (%s)\n\n%s", i+1, cell.CellType, source)
+		}
+		cells = append(cells, sb.String())
+	}
+	return strings.Join(cells, "\n\n"), nil
+}
+
+// text returns the plain text of the output. Rich outputs such as images have none.
+func (o notebookOutput) text() string {
+	switch o.OutputType {
+	case "stream":
+		return strings.TrimRight(string(o.Text), "\n")
+	case "execute_result", "display_data":
+		return strings.TrimRight(string(o.Data["text/plain"]), "\n")
+	case "error":
+		return o.Ename + ": " + o.Evalue
+	}
+	return ""
+}
+
+func truncateOutput(text string) string {
+	truncated := false
+	if len(text) > notebookOutputMaxBytes {
+		text = strings.ToValidUTF8(text[:notebookOutputMaxBytes], "")
+		truncated = true
+	}
+	if lines := strings.Split(text, "\n"); len(lines) > notebookOutputMaxLines {
+		text = strings.Join(lines[:notebookOutputMaxLines], "\n")
+		truncated = true
+	}
+	if truncated {
+		text += "\n[... output truncated ...]"
+	}
+	return text
+}
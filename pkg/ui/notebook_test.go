@@ -0,0 +1,89 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/shutils/lazyreview/pkg/config"
+)
+
+const testNotebook = `{
+  "metadata": {"kernelspec": {"language": "python"}, "language_info": {"name": "python3"}},
+  "cells": [
+    {"cell_type": "markdown", "source": ["# Title\n", "Some text\n"]},
+    {"cell_type": "code", "execution_count": 2, "source": "print('hi')\n", "outputs": [
+      {"output_type": "stream", "text": ["hi\n"]},
+      {"output_type": "display_data", "data": {"image/png": "iVBOR"}},
+      {"output_type": "error", "ename": "ValueError", "evalue": "bad"}
+    ]},
+    {"cell_type": "code", "execution_count": null, "source": "x = 1", "outputs": []}
+  ]
+}`
+
+func TestRenderNotebook(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "analysis.ipynb")
+	if err := os.WriteFile(path, []byte(testNotebook), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	text, numbering, err := renderNotebook(path, config.Config{}, 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "## Cell 1 (markdown)\n\n# Title\nSome text\n\n" +
+		"## Cell 2 (code, In [2])\n\n```python3\nprint('hi')\n```\n\n" +
+		"## Cell 3 (code)\n\n```python3\nx = 1\n```"
+	if text != want {
+		t.Errorf("rendered\n%s\nwant\n%s", text, want)
+	}
+	if numbering != (lineNumbering{}) {
+		t.Errorf("numbering = %+v, want sequential lines", numbering)
+	}
+
+	text, _, err = renderNotebook(path, config.Config{NotebookOutputs: true}, 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, output := range []string{"Output:\n\n```text\nhi\n```", "Output:\n\n```text\nValueError: bad\n```"} {
+		if !strings.Contains(text, output) {
+			t.Errorf("rendered notebook does not contain %q:\n%s", output, text)
+		}
+	}
+	if strings.Contains(text, "iVBOR") {
+		t.Errorf("rich output rendered as text")
+	}
+
+	text, numbering, err = renderNotebook(path, config.Config{}, 60)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if numbering.tailLine == 0 || !strings.Contains(text, "omitted") {
+		t.Errorf("notebook was not truncated: %q", text)
+	}
+
+	if err := os.WriteFile(path, []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := renderNotebook(path, config.Config{}, 1<<20); err == nil {
+		t.Errorf("invalid notebook rendered without an error")
+	}
+}
+
+func TestTruncateOutput(t *testing.T) {
+	if got := truncateOutput("short"); got != "short" {
+		t.Errorf("truncateOutput() = %q, want the output as is", got)
+	}
+	long := strings.Repeat("line\n", notebookOutputMaxLines*2)
+	got := truncateOutput(long)
+	if lines := strings.Count(got, "\n"); lines != notebookOutputMaxLines {
+		t.Errorf("kept %d lines, want %d", lines, notebookOutputMaxLines)
+	}
+	if !strings.HasSuffix(got, "[... output truncated ...]") {
+		t.Errorf("truncated output is not marked: %q", got)
+	}
+	if got := truncateOutput(strings.Repeat("x", notebookOutputMaxBytes*2)); len(got) > notebookOutputMaxBytes+len("\n[... output truncated ...]") {
+		t.Errorf("kept %d bytes, want at most %d", len(got), notebookOutputMaxBytes)
+	}
+}
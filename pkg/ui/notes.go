@@ -0,0 +1,263 @@
+package ui
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/shutils/lazyreview/pkg/config"
+)
+
+// Notes ref holding the reviews of commits, passed to git notes --ref
+const notesRef = "lazyreview"
+
+// First line of the notes written by lazyreview, followed by header fields and a blank line
+const noteMagic = "lazyreview review"
+
+// notesLoadedMsg carries the reviews of the commits of a source found in git notes.
+type notesLoadedMsg struct {
+	generation int
+	reviews    []reviewInfo
+	err        error
+}
+
+// formatNote returns the note of a review version.
+func formatNote(version reviewVersion) string {
+	var sb strings.Builder
+	sb.WriteString(noteMagic + "\n")
+	for _, field := range [][2]string{
+		{"model", version.Model},
+		{"author", version.Author},
+		{"reviewed-at", version.ReviewedAt.UTC().Format(time.RFC3339Nano)},
+		{"payload-hash", version.PayloadHash},
+	} {
+		if field[1] != "" {
+			fmt.Fprintf(&sb, "%s: %s\n", field[0], field[1])
+		}
+	}
+	sb.WriteString("\n")
+	sb.WriteString(version.Review)
+	sb.WriteString("\n")
+	return sb.String()
+}
+
+// parseNote reads a note written by formatNote. Notes written by hand are the review text.
+func parseNote(note string) reviewVersion {
+	header, review, ok := strings.Cut(note, "\n\n")
+	if !ok || !strings.HasPrefix(header, noteMagic+"\n") && header != noteMagic {
+		return reviewVersion{Review: strings.TrimSpace(note)}
+	}
+	version := reviewVersion{Review: strings.TrimRight(review, "\n")}
+	for _, line := range strings.Split(header, "\n")[1:] {
+		key, value, ok := strings.Cut(line, ": ")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "model":
+			version.Model = value
+		case "author":
+			version.Author = value
+		case "reviewed-at":
+			version.ReviewedAt, _ = time.Parse(time.RFC3339Nano, value)
+		case "payload-hash":
+			version.PayloadHash = value
+		}
+	}
+	return version
+}
+
+// resolveCommits returns the commit of each rev that names one in the repository at dir.
+func resolveCommits(dir string, revs []string) (map[string]string, error) {
+	var input strings.Builder
+	var queried []string
+	for _, rev := range revs {
+		if rev == "" || strings.ContainsAny(rev, " \t\r\n") {
+			continue
+		}
+		queried = append(queried, rev)
+		input.WriteString(rev + "^{commit}\n")
+	}
+	commits := map[string]string{}
+	if len(queried) == 0 {
+		return commits, nil
+	}
+	out, err := gitWithInput(dir, input.String(), "cat-file", "--batch-check")
+	if err != nil {
+		return nil, err
+	}
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for i := 0; scanner.Scan() && i < len(queried); i++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 3 && fields[1] == "commit" {
+			commits[queried[i]] = fields[0]
+		}
+	}
+	return commits, scanner.Err()
+}
+
+// annotatedCommits returns the commits that have a note in the notes ref.
+func annotatedCommits(dir string) (map[string]bool, error) {
+	out, err := git(dir, "notes", "--ref="+notesRef, "list")
+	if err != nil {
+		return nil, err
+	}
+	commits := map[string]bool{}
+	for _, line := range strings.Split(out, "\n") {
+		// Each line is the note object followed by the annotated commit
+		if fields := strings.Fields(line); len(fields) == 2 {
+			commits[fields[1]] = true
+		}
+	}
+	return commits, nil
+}
+
+// loadNotesCmd reads the notes of the commits collected by a source as reviews.
+func loadNotesCmd(ctx context.Context, source config.Source, items []list.Item, root string, generation int) tea.Cmd {
+	if !source.ReadsNotes() || len(items) == 0 {
+		return nil
+	}
+	var targets []listItem
+	var params []string
+	for _, item := range items {
+		if item, ok := item.(listItem); ok {
+			item.id = makeHash(root, item)
+			targets = append(targets, item)
+			params = append(params, item.param)
+		}
+	}
+
+	return func() tea.Msg {
+		annotated, err := annotatedCommits(root)
+		if err != nil || len(annotated) == 0 {
+			// A repository without notes has no notes ref
+			return nil
+		}
+		commits, err := resolveCommits(root, params)
+		if err != nil {
+			return notesLoadedMsg{generation: generation, err: err}
+		}
+		var reviews []reviewInfo
+		for _, item := range targets {
+			if ctx.Err() != nil {
+				return nil
+			}
+			commit, ok := commits[item.param]
+			if !ok || !annotated[commit] {
+				continue
+			}
+			note, err := git(root, "notes", "--ref="+notesRef, "show", commit)
+			if err != nil {
+				return notesLoadedMsg{generation: generation, err: err}
+			}
+			review := reviewInfo{ID: item.id, Param: item.param, Key: itemKey(item), Source: item.sourceName, Project: root}
+			reviews = append(reviews, review.addVersion(parseNote(note)))
+		}
+		return notesLoadedMsg{generation: generation, reviews: reviews}
+	}
+}
+
+// handleNotesLoaded adds the reviews found in notes to the review store.
+// Notes of versions the store already has are skipped. They are matched by time, as
+// git normalizes the whitespace of notes.
+func (m *model) handleNotesLoaded(msg notesLoadedMsg) tea.Cmd {
+	if msg.generation != m.collectGeneration {
+		return nil
+	}
+	if msg.err != nil {
+		return func() tea.Msg {
+			return SendErrorMessage("Failed to load reviews from git notes", msg.err)
+		}
+	}
+	var reviews []reviewInfo
+	for _, review := range msg.reviews {
+		if !m.hasVersionAt(review.ID, review.ReviewedAt) {
+			reviews = append(reviews, review)
+		}
+	}
+	if len(reviews) == 0 || m.storage == nil {
+		return nil
+	}
+	if err := m.storage.Import(reviews); err != nil {
+		return func() tea.Msg {
+			return SendErrorMessage("Failed to import reviews from git notes", err)
+		}
+	}
+	_, loadCmd := m.loadReviews()
+	cmds := []tea.Cmd{loadCmd, m.rebuildItemList()}
+	for _, review := range reviews {
+		if index := m.getReviewIndex(review.ID); index != -1 {
+			if err := m.writeSidecar(m.reviewList[index]); err != nil {
+				cmds = append(cmds, func() tea.Msg {
+					return SendErrorMessage("Failed to write review file", err)
+				})
+				break
+			}
+		}
+	}
+	return tea.Batch(cmds...)
+}
+
+func (m *model) hasVersionAt(id string, reviewedAt time.Time) bool {
+	index := m.getReviewIndex(id)
+	if index == -1 {
+		return false
+	}
+	for _, version := range m.reviewList[index].versions() {
+		if version.ReviewedAt.Equal(reviewedAt) {
+			return true
+		}
+	}
+	return false
+}
+
+// writeNoteCmd writes a review to the note of its commit, if the source of the item writes notes.
+// Failed reviews are not written.
+func (m *model) writeNoteCmd(msg reviewMsg) tea.Cmd {
+	source, ok := m.findCollectorSource(msg.source)
+	if !ok || !source.WritesNotes() || msg.version.state() == reviewFailed {
+		return nil
+	}
+	root, param, note := m.projectRoot, msg.param, formatNote(msg.version)
+	return func() tea.Msg {
+		commits, err := resolveCommits(root, []string{param})
+		if err != nil {
+			return SendErrorMessage("Failed to write git note", err)
+		}
+		commit, ok := commits[param]
+		if !ok {
+			return SendErrorMessage(fmt.Sprintf("Failed to write git note: %s is not a commit", param), nil)
+		}
+		if _, err := gitWithInput(root, note, "notes", "--ref="+notesRef, "add", "-f", "-F", "-", commit); err != nil {
+			return SendErrorMessage("Failed to write git note", err)
+		}
+		return nil
+	}
+}
+
+// removeNoteCmd removes the note of a deleted review, if the source of the item writes notes.
+func (m *model) removeNoteCmd(review reviewInfo) tea.Cmd {
+	source, ok := m.findCollectorSource(review.Source)
+	if !ok || !source.WritesNotes() {
+		return nil
+	}
+	root, param := m.projectRoot, review.Param
+	return func() tea.Msg {
+		commits, err := resolveCommits(root, []string{param})
+		if err != nil {
+			return SendErrorMessage("Failed to remove git note", err)
+		}
+		commit, ok := commits[param]
+		if !ok {
+			return nil
+		}
+		if _, err := git(root, "notes", "--ref="+notesRef, "remove", "--ignore-missing", commit); err != nil {
+			return SendErrorMessage("Failed to remove git note", err)
+		}
+		return nil
+	}
+}
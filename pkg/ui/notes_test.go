@@ -0,0 +1,51 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNoteRoundTrip(t *testing.T) {
+	version := reviewVersion{
+		ReviewedAt:  time.Date(2024, 5, 1, 12, 30, 0, 123, time.FixedZone("JST", 9*60*60)),
+		Model:       "gpt-4o",
+		Author:      "Alice <alice@example.com>",
+		PayloadHash: "abc",
+		Review:      "Looks good.\n\nOne nit: rename x.\n",
+	}
+	note := formatNote(version)
+	if !strings.HasPrefix(note, noteMagic+"\n") {
+		t.Errorf("note does not start with %q:\n%s", noteMagic, note)
+	}
+	got := parseNote(note)
+	if !got.ReviewedAt.Equal(version.ReviewedAt) || got.Model != version.Model || got.Author != version.Author || got.PayloadHash != version.PayloadHash {
+		t.Errorf("parseNote() = %+v, want %+v", got, version)
+	}
+	if want := "Looks good.\n\nOne nit: rename x."; got.Review != want {
+		t.Errorf("review = %q, want %q", got.Review, want)
+	}
+}
+
+func TestParseNote(t *testing.T) {
+	tests := []struct {
+		name       string
+		note       string
+		wantReview string
+		wantModel  string
+	}{
+		{"written by hand", "\n  Ship it.\n\n", "Ship it.", ""},
+		{"header only", noteMagic + "\nmodel: m\n", noteMagic + "\nmodel: m", ""},
+		{"no fields", noteMagic + "\n\nok\n", "ok", ""},
+		{"unknown and malformed fields", noteMagic + "\nmodel: m\nreviewer: bob\nbroken\n\nok\n", "ok", "m"},
+		{"magic line with a suffix", noteMagic + "s\nmodel: m\n\nok\n", noteMagic + "s\nmodel: m\n\nok", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseNote(tt.note)
+			if got.Review != tt.wantReview || got.Model != tt.wantModel {
+				t.Errorf("parseNote() = review %q, model %q, want %q, %q", got.Review, got.Model, tt.wantReview, tt.wantModel)
+			}
+		})
+	}
+}
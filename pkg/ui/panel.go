@@ -37,6 +37,8 @@ type panels struct {
 	promptPanel         textarea.Model
 	spinner             spinner.Model
 	messagePanel        viewport.Model
+	commandLogPanel     viewport.Model
+	projectListPanel    list.Model
 }
 
 func NewPanels() panels {
@@ -57,6 +59,8 @@ func NewPanels() panels {
 		promptPanel:         textarea.New(),
 		spinner:             spinner.New(),
 		messagePanel:        viewport.New(0, 0),
+		commandLogPanel:     viewport.New(0, 0),
+		projectListPanel:    list.New([]list.Item{}, list.NewDefaultDelegate(), 0, 0),
 	}
 
 	p.setInitSetting()
@@ -66,6 +70,9 @@ func NewPanels() panels {
 func (p *panels) setInitSetting() {
 	p.sourceListPanel = setListInitSetting(p.sourceListPanel)
 	p.contextListPanel = setListInitSetting(p.contextListPanel)
+	p.projectListPanel = setListInitSetting(p.projectListPanel)
+	// Projects are shown with their root
+	p.projectListPanel.SetDelegate(list.NewDefaultDelegate())
 
 	p.itemListPanel.model.SetShowHelp(false)
 	p.itemListPanel.model.SetShowTitle(false)
@@ -102,6 +109,8 @@ const (
 	StatePanelFocus
 	ContextPanelFocus
 	SourceListPanelFocus
+	CommandLogPanelFocus
+	ProjectListPanelFocus
 	MessagePanelFocus
 	Other
 )
@@ -205,7 +214,7 @@ func (m *model) makeView() string {
 	}
 
 	state := "/"
-	if m.reviewState == Reviewing {
+	if m.reviewState == Reviewing || m.isCollecting() {
 		state = m.panels.spinner.View()
 	}
 
@@ -213,9 +222,17 @@ func (m *model) makeView() string {
 	globalHelp := helpModel.View(m.keyMaps.globalKeyMap)
 	helpString := m.getHelpString(helpModel, globalHelp)
 
-	listPanel := m.buildPanel(m.panels.itemListPanel.model.View(), m.getPanelStyle(ItemListPanelFocus), m.panels.itemListPanel.model.Width(), m.panels.itemListPanel.model.Height(), "List")
-	contentPanel := m.buildPanel(m.panels.itemPreviewPanel.View(), m.getPanelStyle(ContentPanelFocus), m.panels.itemPreviewPanel.Width, m.panels.itemPreviewPanel.Height, "Content")
-	reviewPanel := m.buildPanel(m.panels.itemReviewPanel.View(), m.getPanelStyle(ReviewPanelFocus), m.panels.itemReviewPanel.Width, m.panels.itemReviewPanel.Height, "Review")
+	listTitle := "List"
+	if m.reviewFilter != filterAll {
+		listTitle = "List (" + m.reviewFilter.String() + ")"
+	}
+	listPanel := m.buildPanel(m.panels.itemListPanel.model.View(), m.getPanelStyle(ItemListPanelFocus), m.panels.itemListPanel.model.Width(), m.panels.itemListPanel.model.Height(), listTitle)
+	contentTitle := "Content"
+	if m.showPayload {
+		contentTitle = "Content (payload)"
+	}
+	contentPanel := m.buildPanel(m.panels.itemPreviewPanel.View(), m.getPanelStyle(ContentPanelFocus), m.panels.itemPreviewPanel.Width, m.panels.itemPreviewPanel.Height, contentTitle)
+	reviewPanel := m.buildPanel(m.panels.itemReviewPanel.View(), m.getPanelStyle(ReviewPanelFocus), m.panels.itemReviewPanel.Width, m.panels.itemReviewPanel.Height, m.reviewPanelTitle())
 	reviewStackPanel := m.buildPanel(m.panels.reviewStackPanel.View(), m.getPanelStyle(Other), m.panels.reviewStackPanel.Width, m.panels.reviewStackPanel.Height, "Review stack")
 	configPanel := m.buildPanel(m.panels.configSummaryPanel.View(), m.getPanelStyle(ConfigSummaryPanelFocus), m.panels.configSummaryPanel.Width, m.panels.configSummaryPanel.Height, "Config")
 	configContentPanel := m.buildPanel(m.panels.configDetailPanel.View(), m.getPanelStyle(Other), m.panels.configDetailPanel.Width, m.panels.configDetailPanel.Height, "Config content")
@@ -226,6 +243,8 @@ func (m *model) makeView() string {
 	sourceListPanel := m.buildPanel(m.panels.sourceListPanel.View(), m.getPanelStyle(SourceListPanelFocus), m.panels.sourceListPanel.Width(), m.panels.sourceListPanel.Height(), "Source list")
 	sourceDetailPanel := m.buildPanel(m.panels.sourceDetailPanel.View(), m.getPanelStyle(Other), m.panels.sourceDetailPanel.Width, m.panels.sourceDetailPanel.Height, "Source detail")
 	contextDetailPanel := m.buildPanel(m.panels.contextDetailPanel.View(), m.getPanelStyle(Other), m.panels.contextDetailPanel.Width, m.panels.contextDetailPanel.Height, "Context detail")
+	commandLogPanel := m.buildPanel(m.panels.commandLogPanel.View(), m.getPanelStyle(CommandLogPanelFocus), m.panels.commandLogPanel.Width, m.panels.commandLogPanel.Height, "Command log")
+	projectListPanel := m.buildPanel(m.panels.projectListPanel.View(), m.getPanelStyle(ProjectListPanelFocus), m.panels.projectListPanel.Width(), m.panels.projectListPanel.Height(), "Projects")
 	reviewProgressPanel := m.buildPanel(m.panels.reviewProgressPanel.View(), m.getPanelStyle(ReviewStackProgressPanelFocus), m.panels.reviewProgressPanel.Width, 1, "Review progress")
 
 	primaryPanels := m.buildPrimaryPanels(statePanel, listPanel, reviewProgressPanel, contextPanel, sourceListPanel, configPanel)
@@ -244,6 +263,8 @@ func (m *model) makeView() string {
 		sourceDetailPanel,
 		contextDetailPanel,
 		reviewStackPanel,
+		commandLogPanel,
+		projectListPanel,
 		bottomLine,
 	)
 }
@@ -268,6 +289,10 @@ func (m *model) getHelpString(helpModel help.Model, globalHelp string) string {
 		return MakeBottomLine(globalHelp, helpModel.View(m.keyMaps.contextKeyMap))
 	case SourceListPanelFocus:
 		return MakeBottomLine(globalHelp, helpModel.View(m.keyMaps.sourceListKeyMap))
+	case CommandLogPanelFocus:
+		return MakeBottomLine(globalHelp, helpModel.View(m.keyMaps.commandLogKeyMap))
+	case ProjectListPanelFocus:
+		return MakeBottomLine(globalHelp, helpModel.View(m.keyMaps.projectListKeyMap))
 	default:
 		return ""
 	}
@@ -290,6 +315,8 @@ func (m *model) buildWindowBasedOnFocus(primaryPanels,
 	sourceDetailPanel,
 	contextDetailPanel,
 	reviewStackPanel,
+	commandLogPanel,
+	projectListPanel,
 	bottomLine string,
 ) string {
 	switch m.focusState {
@@ -309,6 +336,10 @@ func (m *model) buildWindowBasedOnFocus(primaryPanels,
 		return m.buildWindow(primaryPanels, contextDetailPanel, bottomLine)
 	case ReviewStackProgressPanelFocus:
 		return m.buildWindow(primaryPanels, reviewStackPanel, bottomLine)
+	case CommandLogPanelFocus:
+		return m.buildWindow(primaryPanels, commandLogPanel, bottomLine)
+	case ProjectListPanelFocus:
+		return m.buildWindow(primaryPanels, projectListPanel, bottomLine)
 	default:
 		return ""
 	}
@@ -438,6 +469,11 @@ func (m *model) setSecondaryPanelSizes() {
 
 	m.panels.contextDetailPanel.Width = secondlyAreaWidth - borderWidth*2
 	m.panels.contextDetailPanel.Height = m.winSize.height - borderHeight*2 - footerHeight
+
+	m.panels.commandLogPanel.Width = secondlyAreaWidth - borderWidth*2
+	m.panels.commandLogPanel.Height = m.winSize.height - borderHeight*2 - footerHeight
+
+	m.panels.projectListPanel.SetSize(secondlyAreaWidth-borderWidth*2, m.winSize.height-borderHeight*2-footerHeight)
 }
 
 func (m *model) buildPrimaryPanels(statePanel, listPanel, reviewStackPanel, contextPanel, sourceListPanel, configPanel string) string {
@@ -489,7 +525,7 @@ func getRendered(text string, style string, width int) string {
 }
 
 func isFocusPrimary(state FocusState) bool {
-	if state == ItemListPanelFocus || state == ConfigSummaryPanelFocus || state == StatePanelFocus || state == ContextPanelFocus || state == ReviewStackProgressPanelFocus || state == SourceListPanelFocus {
+	if state == ItemListPanelFocus || state == ConfigSummaryPanelFocus || state == StatePanelFocus || state == ContextPanelFocus || state == ReviewStackProgressPanelFocus || state == SourceListPanelFocus || state == CommandLogPanelFocus || state == ProjectListPanelFocus {
 		return true
 	}
 	return false
@@ -0,0 +1,81 @@
+package ui
+
+import (
+	"container/list"
+)
+
+const previewCacheSize = 100
+
+// previewCacheEntry holds the preview of an item and its rendered review.
+type previewCacheEntry struct {
+	id              string
+	sourceName      string
+	content         string
+	hasContent      bool
+	payload         string
+	hasPayload      bool
+	review          string // Raw review text the rendered review was made from
+	reviewWidth     int
+	reviewFormatted bool // The review is a diff, which is not rendered as markdown
+	renderedReview  string
+	hasReview       bool
+}
+
+func (e previewCacheEntry) shownContent(showPayload bool) string {
+	if showPayload {
+		return e.payload
+	}
+	return e.content
+}
+
+// previewCache is a LRU cache of previews keyed by item id.
+// It is only accessed from Update, so it is not guarded by a mutex.
+type previewCache struct {
+	entries map[string]*list.Element
+	order   *list.List
+}
+
+func newPreviewCache() *previewCache {
+	return &previewCache{
+		entries: map[string]*list.Element{},
+		order:   list.New(),
+	}
+}
+
+func (c *previewCache) get(id string) (previewCacheEntry, bool) {
+	elem, ok := c.entries[id]
+	if !ok {
+		return previewCacheEntry{}, false
+	}
+	c.order.MoveToFront(elem)
+	return elem.Value.(previewCacheEntry), true
+}
+
+func (c *previewCache) put(entry previewCacheEntry) {
+	if elem, ok := c.entries[entry.id]; ok {
+		elem.Value = entry
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[entry.id] = c.order.PushFront(entry)
+	if c.order.Len() > previewCacheSize {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(previewCacheEntry).id)
+	}
+}
+
+// removeSource drops the entries of items collected by the source.
+func (c *previewCache) removeSource(sourceName string) {
+	for id, elem := range c.entries {
+		if elem.Value.(previewCacheEntry).sourceName == sourceName {
+			c.order.Remove(elem)
+			delete(c.entries, id)
+		}
+	}
+}
+
+func (c *previewCache) clear() {
+	c.entries = map[string]*list.Element{}
+	c.order.Init()
+}
@@ -1,70 +1,146 @@
 package ui
 
 import (
-	"bytes"
+	"context"
 	"fmt"
-	"net/http"
-	"os"
-	"os/exec"
+	"time"
 
 	"github.com/shutils/lazyreview/pkg/config"
 )
 
-func defaultPreviewer(param string) string {
+const notTextFallback = "This item is not text"
+
+// defaultPreviewer shows the file at param with syntax highlighting and line numbers.
+// Notebooks are rendered cell by cell, and images and binary files are described
+// by a summary of their file info.
+func defaultPreviewer(param string, conf config.Config) string {
 	if param == "" {
 		return "Error: No param"
 	}
-	var fallbackText = "This item is not text"
-	content, err := os.ReadFile(param)
+	if isImageFile(param) {
+		return imageSummary(param, conf)
+	}
+	if isNotebookFile(param) {
+		if text, numbering, err := renderNotebook(param, conf, maxPreviewSize(conf)); err == nil {
+			return highlightText("notebook.md", text, conf.PreviewTheme, numbering)
+		}
+	}
+	tf, err := readTextFile(param, maxPreviewSize(conf))
 	if err != nil {
-		return string(fallbackText)
-	}
-	ty := http.DetectContentType(content)
-	switch ty {
-	case "text/plain; charset=utf-8":
-		return string(content)
-	case "text/xml; charset=utf-8":
-		return string(content)
-	case "text/html; charset=utf-8":
-		return string(content)
-	default:
-		return string(fallbackText)
+		return fmt.Sprintf("Error: %v", err)
+	}
+	if tf.binary {
+		return fileSummary(param, tf)
 	}
+	return highlightText(param, tf.text, conf.PreviewTheme, tf.numbering)
 }
 
-func customPreviewer(cmds []string, param string) string {
+// defaultPayload returns the plain text of the file at param, so that line numbers
+// referenced by the AI match the ones shown by defaultPreviewer.
+// Images are sent separately, so only their summary is part of the text.
+// Notebooks are sent in the same compact form as their preview.
+func defaultPayload(param string, conf config.Config) string {
 	if param == "" {
 		return "Error: No param"
 	}
+	if isImageFile(param) {
+		return imageSummary(param, conf)
+	}
+	if isNotebookFile(param) {
+		if text, numbering, err := renderNotebook(param, conf, maxPayloadSize(conf)); err == nil {
+			if conf.PayloadLineNumbers {
+				return numberLines(text, numbering)
+			}
+			return text
+		}
+	}
+	tf, err := readTextFile(param, maxPayloadSize(conf))
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	if tf.binary {
+		return fileSummary(param, tf)
+	}
+	if conf.PayloadLineNumbers {
+		return numberLines(tf.text, tf.numbering)
+	}
+	return tf.text
+}
+
+// customPreviewer runs the previewer command for param.
+// The param is appended as the last argument unless the command contains a placeholder.
+func customPreviewer(ctx context.Context, kind string, cmds []string, param string, sourceName string, dir string, vars commandVars) (string, commandResult) {
+	if param == "" {
+		return "Error: No param", commandResult{}
+	}
 	if len(cmds) == 0 {
-		return "Error: No previewer"
+		return "Error: No previewer", commandResult{}
+	}
+	args, used := vars.expand(cmds)
+	if !used {
+		args = append(args, param)
+	}
+	result := runCommand(ctx, kind, sourceName, dir, args, vars)
+	if result.failed() {
+		return fmt.Sprintf("Error: %v (exit code %d, %s)\n\n%s", result.err, result.exitCode, result.duration.Round(time.Millisecond), result.stderr), result
 	}
-	args := cmds[1:]
-	args = append(args, param)
-	cmd := exec.Command(cmds[0], args...)
 
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+	output := result.stdout
+	if len(result.stderr) > 0 {
+		output += "\n" + result.stderr
+	}
+	return output, result
+}
 
-	err := cmd.Run()
-	if err != nil {
-		return fmt.Sprintf("Error: %v", err)
+// payloadContent returns the content of the item sent to the AI.
+// A followed item without a payload command sends the last lines of its previewer output.
+func (m *model) payloadContent(item listItem) string {
+	if follow := m.follow; follow != nil && follow.id == item.id {
+		if source, _ := getSource(item.sourceName, m.conf.Sources); len(source.Payload) == 0 {
+			content, _ := follow.buffer.snapshot()
+			return content
+		}
 	}
+	return loadPayload(context.Background(), item, m.conf, m.commandLog)
+}
 
-	output := stdout.String()
-	if stderr.Len() > 0 {
-		output += "\n" + stderr.String()
+// loadPayload runs the payload command of the item's source.
+// Sources without a payload command use the previewer output as the payload,
+// which is cut off after a short time for follow sources.
+func loadPayload(ctx context.Context, item listItem, conf config.Config, log *commandLog) string {
+	if item.sourceName != "" {
+		source, _ := getSource(item.sourceName, conf.Sources)
+		if len(source.Payload) != 0 {
+			vars := itemCommandVars(item, sourceTarget(conf, source))
+			content, result := customPreviewer(ctx, "payload", source.Payload, item.param, item.sourceName, conf.WorkDir, vars)
+			if ctx.Err() == nil {
+				log.add(result)
+			}
+			return content
+		}
+		if len(source.Previewer) != 0 && source.Follow {
+			return followSnapshot(ctx, item, source, conf, log)
+		}
+		if len(source.Previewer) != 0 {
+			return loadPreview(ctx, item, conf, log)
+		}
 	}
-	return output
+	return defaultPayload(item.param, conf)
 }
 
-func previewContent(item listItem, sources []config.Source) string {
+// loadPreview runs the previewer of the item's source and records its failure in log.
+// Failures caused by cancelling ctx are not recorded.
+func loadPreview(ctx context.Context, item listItem, conf config.Config, log *commandLog) string {
 	if item.sourceName != "" {
-		source, _ := getSource(item.sourceName, sources)
+		source, _ := getSource(item.sourceName, conf.Sources)
 		if len(source.Previewer) != 0 {
-			return customPreviewer(source.Previewer, item.param)
+			vars := itemCommandVars(item, sourceTarget(conf, source))
+			content, result := customPreviewer(ctx, "previewer", source.Previewer, item.param, item.sourceName, conf.WorkDir, vars)
+			if ctx.Err() == nil {
+				log.add(result)
+			}
+			return content
 		}
 	}
-	return defaultPreviewer(item.param)
+	return defaultPreviewer(item.param, conf)
 }
@@ -0,0 +1,278 @@
+package ui
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/shutils/lazyreview/pkg/atomicfile"
+	"github.com/shutils/lazyreview/pkg/config"
+)
+
+// projectEntry is a project recorded in the project registry.
+type projectEntry struct {
+	Root       string    `json:"root"`
+	Target     string    `json:"target,omitempty"`  // Absolute target the project was last opened with
+	WorkDir    string    `json:"workDir,omitempty"` // Directory its commands ran in
+	Store      string    `json:"store"`
+	LastOpened time.Time `json:"lastOpened"`
+}
+
+// projectItem is a project in the project switcher.
+type projectItem struct {
+	entry   projectEntry
+	reviews int
+	current bool
+}
+
+func (i projectItem) Title() string {
+	title := filepath.Base(i.entry.Root)
+	if i.current {
+		title += " (current)"
+	}
+	return fmt.Sprintf("%s  %d reviews", title, i.reviews)
+}
+func (i projectItem) Description() string { return i.entry.Root }
+func (i projectItem) FilterValue() string { return i.entry.Root }
+
+func projectRegistryPath() string {
+	return config.DataPath("projects.json")
+}
+
+func loadProjects(registry string) ([]projectEntry, error) {
+	data, err := os.ReadFile(registry)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var projects []projectEntry
+	if err := json.Unmarshal(data, &projects); err != nil {
+		return nil, err
+	}
+	return projects, nil
+}
+
+// registerProject records the project of entry in the registry, most recently opened first.
+func registerProject(registry string, entry projectEntry) error {
+	unlock, err := atomicfile.Lock(registry)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	projects, err := loadProjects(registry)
+	if err != nil {
+		return err
+	}
+	entry.LastOpened = time.Now()
+	updated := []projectEntry{entry}
+	for _, project := range projects {
+		if project.Root != entry.Root {
+			updated = append(updated, project)
+		}
+	}
+	data, err := json.MarshalIndent(updated, "", "  ")
+	if err != nil {
+		return err
+	}
+	return atomicfile.WriteFile(registry, data, 0644)
+}
+
+// countReviews returns the number of reviews of the project in store, or 0 if it can not be read.
+func countReviews(store string, root string) int {
+	count, err := countStoredReviews(store, root)
+	if err != nil {
+		return 0
+	}
+	return count
+}
+
+// importLegacyReviews copies the reviews of the project at root from the review file shared
+// by all projects in older versions. The file is not modified. Reviews written before reviews
+// recorded their project are imported if their param exists in the project.
+func importLegacyReviews(legacy string, root string, sourceNames []string) ([]reviewInfo, error) {
+	data, err := os.ReadFile(legacy)
+	if err != nil {
+		return nil, err
+	}
+	reviews, migrated, err := decodeReviewStore(data, root, sourceNames)
+	if err != nil {
+		return nil, err
+	}
+	var imported []reviewInfo
+	for _, review := range reviews {
+		if review.Project != root {
+			continue
+		}
+		// Reviews of every project were migrated to this one, so only those of its items are kept
+		if migrated && !paramExists(review.Param, root) {
+			continue
+		}
+		imported = append(imported, review)
+	}
+	return imported, nil
+}
+
+func paramExists(param string, root string) bool {
+	if param == "" {
+		return false
+	}
+	if !filepath.IsAbs(param) {
+		if _, err := os.Stat(param); err == nil {
+			return true
+		}
+		param = filepath.Join(root, param)
+	}
+	_, err := os.Stat(param)
+	return err == nil
+}
+
+func (m *model) getProjectItems() []list.Item {
+	projects, _ := loadProjects(projectRegistryPath())
+	sort.SliceStable(projects, func(i, j int) bool {
+		return projects[i].LastOpened.After(projects[j].LastOpened)
+	})
+	items := make([]list.Item, len(projects))
+	for i, project := range projects {
+		items[i] = projectItem{
+			entry:   project,
+			reviews: countReviews(project.Store, project.Root),
+			current: project.Root == m.projectRoot,
+		}
+	}
+	return items
+}
+
+func (m *model) FocusProjectListPanel() (tea.Model, tea.Cmd) {
+	m.panels.projectListPanel.SetItems(m.getProjectItems())
+	m.panels.projectListPanel.Select(0)
+	return m.focusPanel(ProjectListPanelFocus)
+}
+
+func (m *model) ProjectListCursorDown() (tea.Model, tea.Cmd) {
+	m.panels.projectListPanel.CursorDown()
+	return *m, nil
+}
+
+func (m *model) ProjectListCursorUp() (tea.Model, tea.Cmd) {
+	m.panels.projectListPanel.CursorUp()
+	return *m, nil
+}
+
+// SwitchProject makes the selected project the target, loading its reviews and collecting its items.
+// The target and the directory commands run in are restored from the registry, so that the same
+// items are collected as when lazyreview was started in the project. The working directory of the
+// process is left alone, as it is shared by the commands running in the background.
+func (m *model) SwitchProject() (tea.Model, tea.Cmd) {
+	selected, ok := m.panels.projectListPanel.SelectedItem().(projectItem)
+	if !ok {
+		return m.focusPanel(ItemListPanelFocus)
+	}
+	if m.reviewState == Reviewing {
+		return m, func() tea.Msg {
+			return showMessageMsg{message: "Wait for the reviews to finish before switching projects"}
+		}
+	}
+	root := selected.entry.Root
+	// Projects registered by older versions have neither
+	target, workDir := selected.entry.Target, selected.entry.WorkDir
+	if target == "" {
+		target = root
+	}
+	if workDir == "" {
+		workDir = root
+	}
+	if info, err := os.Stat(target); err != nil || !info.IsDir() {
+		return m, func() tea.Msg {
+			return SendErrorMessage("Failed to switch project", fmt.Errorf("%s is not a directory", target))
+		}
+	}
+
+	m.stopFollowing()
+	m.conf.Target = target
+	m.conf.WorkDir = workDir
+	m.targetDir = target
+	m.projectRoot = root
+	m.outputFile = m.conf.ReviewStorePath(root)
+	m.collectedItems = map[string][]list.Item{}
+	m.staleItems = map[string]bool{}
+	m.staleChecks = map[string]staleCheck{}
+	m.reviewHistory = reviewHistoryView{}
+	m.panels.contextListPanel.SetItems([]list.Item{})
+	m.panels.configDetailPanel.SetContent(strings.Join(m.conf.ToStringArray(), "\n"))
+
+	_, loadCmd := m.openProjectStore()
+	_, focusCmd := m.focusPanel(ItemListPanelFocus)
+	return m, tea.Batch(loadCmd, m.rebuildItemList(), m.startCollecting(), m.restartWatching(), focusCmd)
+}
+
+// openProjectStore loads the reviews of the current project and records it in the registry.
+// A project opened for the first time imports its reviews from the store shared by older versions.
+func (m *model) openProjectStore() (*model, tea.Cmd) {
+	m.author = reviewAuthor(m.conf.Author, m.projectRoot)
+	// An explicit output is shared by every project, as the legacy store was
+	newStore := false
+	if m.conf.Output == "" {
+		_, err := os.Stat(m.outputFile)
+		newStore = os.IsNotExist(err)
+	}
+	if err := m.openStorage(); err != nil {
+		m.reviewList = []reviewInfo{}
+		return m, func() tea.Msg {
+			return SendErrorMessage("Failed to open the review store", err)
+		}
+	}
+	if newStore {
+		reviews, err := importLegacyReviews(config.LegacyReviewStorePath(), m.projectRoot, m.sourceNames())
+		if err == nil && len(reviews) != 0 {
+			if err := m.storage.Import(reviews); err != nil {
+				return m, func() tea.Msg {
+					return SendErrorMessage("Failed to import reviews", err)
+				}
+			}
+		}
+	}
+	entry := projectEntry{Root: m.projectRoot, Target: absPath(m.conf.Target, m.conf.WorkDir), WorkDir: absPath(".", m.conf.WorkDir), Store: m.outputFile}
+	if err := registerProject(projectRegistryPath(), entry); err != nil {
+		m.commandLog.add(commandResult{
+			kind:      "project registry",
+			args:      []string{projectRegistryPath()},
+			startedAt: time.Now(),
+			err:       err,
+		})
+	}
+	return m.loadReviews()
+}
+
+// absPath returns path as an absolute path, resolving relative paths against dir,
+// or the working directory if dir is empty.
+func absPath(path string, dir string) string {
+	if dir != "" && !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	return abs
+}
+
+func (m *model) sourceNames() []string {
+	return sourceNames(m.conf.Sources)
+}
+
+func sourceNames(sources []config.Source) []string {
+	var names []string
+	for _, source := range sources {
+		names = append(names, source.Name)
+	}
+	return names
+}
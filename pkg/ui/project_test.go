@@ -0,0 +1,59 @@
+package ui
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCountStoredReviewsDoesNotMigrate(t *testing.T) {
+	const root = "/project"
+	dir := t.TempDir()
+
+	path := filepath.Join(dir, "reviews.json")
+	legacy, err := json.Marshal([]reviewInfo{
+		{ID: legacyID("a.go", ""), Param: "a.go", Review: "a"},
+		{ID: legacyID("b.go", ""), Param: "b.go", Review: "b"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, legacy, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if count, err := countStoredReviews(path, root); err != nil || count != 2 {
+		t.Errorf("countStoredReviews() = %d, %v, want 2", count, err)
+	}
+	if data, err := os.ReadFile(path); err != nil || !bytes.Equal(data, legacy) {
+		t.Errorf("legacy file was rewritten")
+	}
+	if _, err := os.Stat(path + reviewStoreBackupSuffix); !os.IsNotExist(err) {
+		t.Errorf("legacy file was backed up")
+	}
+
+	missing := sqliteScheme + filepath.Join(dir, "missing.db")
+	if count, err := countStoredReviews(missing, root); err != nil || count != 0 {
+		t.Errorf("countStoredReviews() of a missing database = %d, %v, want 0", count, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "missing.db")); !os.IsNotExist(err) {
+		t.Errorf("missing database was created")
+	}
+
+	db := filepath.Join(dir, "reviews.db")
+	storage, err := openSQLiteStorage(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, review := range []reviewInfo{{ID: "1", Param: "a.go", Project: root}, {ID: "2", Param: "b.go", Project: "/other"}} {
+		if _, err := storage.AddVersion(review, reviewVersion{ReviewedAt: time.Unix(1, 0), Review: "ok"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	storage.Close()
+	if count, err := countStoredReviews(sqliteScheme+db, root); err != nil || count != 1 {
+		t.Errorf("countStoredReviews() of a database = %d, %v, want 1", count, err)
+	}
+}
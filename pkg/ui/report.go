@@ -0,0 +1,347 @@
+package ui
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html"
+	"path/filepath"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/shutils/lazyreview/pkg/atomicfile"
+	"github.com/shutils/lazyreview/pkg/config"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+const reportStyle = `body{font-family:-apple-system,BlinkMacSystemFont,"Segoe UI",sans-serif;line-height:1.6;max-width:960px;margin:2rem auto;padding:0 1rem;color:#1f2328}
+h1,h2{border-bottom:1px solid #d0d7de;padding-bottom:.3em}
+table{border-collapse:collapse}
+td,th{border:1px solid #d0d7de;padding:.3em .8em;text-align:left}
+code{background:#f6f8fa;padding:.1em .3em;border-radius:4px}
+pre{background:#f6f8fa;padding:1em;overflow:auto;border-radius:6px}
+pre code{padding:0}
+details{margin:1em 0}`
+
+// reportItem is a reviewed item in a report.
+type reportItem struct {
+	title   string
+	item    listItem // Used to load the payload
+	review  reviewInfo
+	payload string
+}
+
+// reportFormat returns the report format for name, defaulting to Markdown.
+func reportFormat(name string) (string, error) {
+	switch name {
+	case "", config.ReportMarkdown, "md":
+		return config.ReportMarkdown, nil
+	case config.ReportHTML, config.ReportSARIF, config.ReportRDJSON, config.ReportBundle:
+		return name, nil
+	default:
+		return "", fmt.Errorf("unknown report format %q", name)
+	}
+}
+
+func reportExtension(format string) string {
+	switch format {
+	case config.ReportHTML:
+		return ".html"
+	case config.ReportSARIF:
+		return ".sarif"
+	case config.ReportRDJSON:
+		return ".rdjson"
+	case config.ReportBundle:
+		return ".json"
+	default:
+		return ".md"
+	}
+}
+
+// loadReportPayloads loads the payload of every item, as it is sent for a review.
+func loadReportPayloads(ctx context.Context, items []reportItem, conf config.Config, log *commandLog) {
+	for i := range items {
+		if ctx.Err() != nil {
+			return
+		}
+		items[i].payload = loadPayload(ctx, items[i].item, conf, log)
+	}
+}
+
+// renderReport renders the reviews of items as a single document with a table of contents,
+// as the findings of a code scanning format, or as a bundle to import elsewhere.
+func renderReport(items []reportItem, root string, conf config.Config, format string, now time.Time) (string, error) {
+	switch format {
+	case config.ReportSARIF:
+		return reportSARIF(items, root)
+	case config.ReportRDJSON:
+		return reportRDJSON(items, root)
+	case config.ReportBundle:
+		return renderBundle(items, root, reviewAuthor(conf.Author, root), now)
+	}
+	if format == config.ReportHTML {
+		return reportHTML(items, root, conf.ModelCost, now)
+	}
+	return reportMarkdown(items, root, conf.ModelCost, now), nil
+}
+
+// reportSummary returns the facts shown under the title of a report.
+func reportSummary(items []reportItem, cost config.ModelCost, now time.Time) []string {
+	var tokens int64
+	var total float64
+	for _, item := range items {
+		usage := latestVersion(item.review).Usage
+		tokens += usage.PromptTokens + usage.CompletionTokens
+		total += reviewCost(usage.PromptTokens, usage.CompletionTokens, cost)
+	}
+	summary := []string{
+		"Generated " + now.Local().Format("2006-01-02 15:04"),
+		fmt.Sprintf("%d reviews", len(items)),
+		fmt.Sprintf("%d tokens", tokens),
+	}
+	if hasModelCost(cost) {
+		summary = append(summary, formatCost(total))
+	}
+	return summary
+}
+
+func reportMarkdown(items []reportItem, root string, cost config.ModelCost, now time.Time) string {
+	var sb strings.Builder
+	sb.WriteString("# Review report\n\n")
+	fmt.Fprintf(&sb, "Project: `%s`  \n", root)
+	sb.WriteString(strings.Join(reportSummary(items, cost, now), " · ") + "\n\n")
+
+	sb.WriteString("## Contents\n\n")
+	for i, item := range items {
+		fmt.Fprintf(&sb, "%d. [%s](#%s)\n", i+1, escapeLinkText(html.EscapeString(item.title)), reportAnchor(i))
+	}
+
+	for i, item := range items {
+		sb.WriteString("\n---\n\n")
+		fmt.Fprintf(&sb, "<a id=\"%s\"></a>\n\n", reportAnchor(i))
+		fmt.Fprintf(&sb, "## %d. %s\n\n", i+1, html.EscapeString(item.title))
+		sb.WriteString("| | |\n|---|---|\n")
+		for _, row := range reportMetadata(item.review, cost) {
+			value := row[1]
+			if row[0] == "Item" {
+				value = "`" + value + "`"
+			}
+			fmt.Fprintf(&sb, "| %s | %s |\n", row[0], escapeTableCell(value))
+		}
+		sb.WriteString("\n")
+		sb.WriteString(strings.TrimSpace(item.review.Review))
+		sb.WriteString("\n")
+		if item.payload != "" {
+			fence := codeFence(item.payload)
+			sb.WriteString("\n<details>\n<summary>Payload</summary>\n\n")
+			fmt.Fprintf(&sb, "%s%s\n%s\n%s\n", fence, payloadLanguage(item), strings.TrimRight(item.payload, "\n"), fence)
+			sb.WriteString("\n</details>\n")
+		}
+	}
+	return sb.String()
+}
+
+// reportMetadata returns the rows of the table describing a review.
+func reportMetadata(review reviewInfo, cost config.ModelCost) [][2]string {
+	version := latestVersion(review)
+	rows := [][2]string{{"Item", review.Param}}
+	if review.Source != "" {
+		rows = append(rows, [2]string{"Source", review.Source})
+	}
+	if version.Author != "" {
+		rows = append(rows, [2]string{"Author", version.Author})
+	}
+	if version.Model != "" {
+		rows = append(rows, [2]string{"Model", version.Model})
+	}
+	if !version.ReviewedAt.IsZero() {
+		rows = append(rows, [2]string{"Reviewed", version.ReviewedAt.Local().Format("2006-01-02 15:04")})
+	}
+	if tokens := version.Usage.PromptTokens + version.Usage.CompletionTokens; tokens != 0 {
+		rows = append(rows, [2]string{"Tokens", fmt.Sprintf("%d (input %d, output %d)", tokens, version.Usage.PromptTokens, version.Usage.CompletionTokens)})
+		if hasModelCost(cost) {
+			rows = append(rows, [2]string{"Cost", formatCost(reviewCost(version.Usage.PromptTokens, version.Usage.CompletionTokens, cost))})
+		}
+	}
+	if versions := len(review.versions()); versions > 1 {
+		rows = append(rows, [2]string{"Versions", fmt.Sprint(versions)})
+	}
+	return rows
+}
+
+func payloadLanguage(item reportItem) string {
+	return strings.TrimPrefix(filepath.Ext(item.review.Param), ".")
+}
+
+// reportHTML renders a report as a self-contained HTML document. The document is built from
+// escaped strings, and reviews are rendered from Markdown without their raw HTML, so that
+// reviewed content and model output can not inject markup or load remote resources.
+func reportHTML(items []reportItem, root string, cost config.ModelCost, now time.Time) (string, error) {
+	md := goldmark.New(goldmark.WithExtensions(extension.GFM))
+	var sb strings.Builder
+	sb.WriteString("<h1>Review report</h1>\n")
+	fmt.Fprintf(&sb, "<p>Project: <code>%s</code><br>\n%s</p>\n", html.EscapeString(root), html.EscapeString(strings.Join(reportSummary(items, cost, now), " · ")))
+
+	sb.WriteString("<h2>Contents</h2>\n<ol>\n")
+	for i, item := range items {
+		fmt.Fprintf(&sb, "<li><a href=\"#%s\">%s</a></li>\n", reportAnchor(i), html.EscapeString(item.title))
+	}
+	sb.WriteString("</ol>\n")
+
+	for i, item := range items {
+		sb.WriteString("<hr>\n")
+		fmt.Fprintf(&sb, "<h2 id=\"%s\">%d. %s</h2>\n", reportAnchor(i), i+1, html.EscapeString(item.title))
+		sb.WriteString("<table>\n")
+		for _, row := range reportMetadata(item.review, cost) {
+			value := html.EscapeString(row[1])
+			if row[0] == "Item" {
+				value = "<code>" + value + "</code>"
+			}
+			fmt.Fprintf(&sb, "<tr><th>%s</th><td>%s</td></tr>\n", html.EscapeString(row[0]), value)
+		}
+		sb.WriteString("</table>\n")
+		var review bytes.Buffer
+		if err := md.Convert([]byte(strings.TrimSpace(item.review.Review)), &review); err != nil {
+			return "", err
+		}
+		sb.Write(review.Bytes())
+		if item.payload != "" {
+			class := ""
+			if language := payloadLanguage(item); language != "" {
+				class = fmt.Sprintf(" class=\"language-%s\"", html.EscapeString(language))
+			}
+			fmt.Fprintf(&sb, "<details>\n<summary>Payload</summary>\n<pre><code%s>%s</code></pre>\n</details>\n",
+				class, html.EscapeString(strings.TrimRight(item.payload, "\n")))
+		}
+	}
+	return fmt.Sprintf("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>%s</title>\n<style>%s</style>\n</head>\n<body>\n%s</body>\n</html>\n",
+		html.EscapeString("Review report: "+filepath.Base(root)), reportStyle, sb.String()), nil
+}
+
+// finishedReview returns review as of its latest finished version, without the failed
+// versions made after it. ok is false if every version failed.
+func finishedReview(review reviewInfo) (reviewInfo, bool) {
+	versions := review.versions()
+	for i := len(versions) - 1; i >= 0; i-- {
+		if versions[i].state() == reviewFailed {
+			continue
+		}
+		review.History = versions[: i+1 : i+1]
+		review.Review = versions[i].Review
+		review.ReviewedAt = versions[i].ReviewedAt
+		review.PayloadHash = versions[i].PayloadHash
+		review.State = reviewFinished
+		return review, true
+	}
+	return review, false
+}
+
+func latestVersion(review reviewInfo) reviewVersion {
+	versions := review.versions()
+	if len(versions) == 0 {
+		return reviewVersion{}
+	}
+	return versions[len(versions)-1]
+}
+
+// reviewCost returns the cost in dollars of the tokens, as shown in the State panel.
+func reviewCost(promptTokens int64, completionTokens int64, cost config.ModelCost) float64 {
+	return (float64(promptTokens)*cost.Input + float64(completionTokens)*cost.Output) / 1000_000
+}
+
+func hasModelCost(cost config.ModelCost) bool {
+	return cost.Input != 0 && cost.Output != 0
+}
+
+func formatCost(cost float64) string {
+	return fmt.Sprintf("$%.4f", cost)
+}
+
+func reportAnchor(index int) string {
+	return fmt.Sprintf("review-%d", index+1)
+}
+
+// codeFence returns a fence longer than any run of backticks in text.
+func codeFence(text string) string {
+	longest, run := 0, 0
+	for _, r := range text {
+		if r == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	return strings.Repeat("`", max(3, longest+1))
+}
+
+func escapeLinkText(text string) string {
+	return strings.NewReplacer("[", `\[`, "]", `\]`).Replace(text)
+}
+
+func escapeTableCell(text string) string {
+	return strings.NewReplacer("|", `\|`, "\n", " ").Replace(text)
+}
+
+// reportItems returns the reviewed items shown in the item list, in the order of the list.
+// Failed reviews are reported as of their latest finished version.
+func (m *model) reportItems() []reportItem {
+	var items []reportItem
+	for _, item := range m.panels.itemListPanel.model.VisibleItems() {
+		item, ok := item.(listItem)
+		if !ok {
+			continue
+		}
+		index := m.getReviewIndex(item.id)
+		if index == -1 {
+			continue
+		}
+		if review, ok := finishedReview(m.reviewList[index]); ok {
+			items = append(items, reportItem{
+				title:  item.plainTitle(),
+				item:   item,
+				review: review,
+			})
+		}
+	}
+	return items
+}
+
+// ExportReport writes the reviews of the items shown in the list to a report in the report directory.
+func (m *model) ExportReport() (tea.Model, tea.Cmd) {
+	items := m.reportItems()
+	if len(items) == 0 {
+		return m, func() tea.Msg {
+			return showMessageMsg{message: "No reviews to export"}
+		}
+	}
+	format, err := reportFormat(m.conf.ReportFormat)
+	if err != nil {
+		return m, func() tea.Msg {
+			return SendErrorMessage("Failed to export reviews", err)
+		}
+	}
+	dir := m.conf.ReportDir
+	if dir == "" {
+		dir = config.DataPath("reports")
+	}
+	now := time.Now()
+	path := filepath.Join(dir, filepath.Base(m.projectRoot)+"-"+now.Format("20060102-150405")+reportExtension(format))
+	conf, root, log := m.conf, m.projectRoot, m.commandLog
+
+	return m, func() tea.Msg {
+		if conf.ReportPayload {
+			loadReportPayloads(context.Background(), items, conf, log)
+		}
+		report, err := renderReport(items, root, conf, format, now)
+		if err == nil {
+			err = atomicfile.WriteFile(path, []byte(report), 0644)
+		}
+		if err != nil {
+			return SendErrorMessage("Failed to export reviews", err)
+		}
+		return showMessageMsg{message: fmt.Sprintf("Exported %d reviews to %s", len(items), path)}
+	}
+}
@@ -1,10 +1,10 @@
 package ui
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
-	"os"
 	"strings"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/openai/openai-go"
@@ -13,10 +13,16 @@ import (
 
 // JSONレビュー情報
 type reviewInfo struct {
-	ID     string `json:"id"`
-	Param  string `json:"param"`
-	Review string `json:"review"`
-	State  string `json:"state"`
+	ID          string          `json:"id"`
+	Param       string          `json:"param"`
+	Key         string          `json:"key,omitempty"` // Collector id, path or param the id is made from
+	Source      string          `json:"source,omitempty"`
+	Project     string          `json:"project,omitempty"`
+	Review      string          `json:"review"`
+	State       string          `json:"state"`
+	ReviewedAt  time.Time       `json:"reviewedAt"`
+	PayloadHash string          `json:"payloadHash,omitempty"` // Hash of the payload sent for the review
+	History     []reviewVersion `json:"history,omitempty"`
 }
 
 type ReviewState int
@@ -26,8 +32,12 @@ type reviewStateMsg struct {
 }
 
 type reviewMsg struct {
-	id      string
-	content string
+	id            string
+	param         string
+	key           string
+	source        string
+	instantPrompt string // Added to the prompt history
+	version       reviewVersion
 }
 
 type reviewStackMsg struct {
@@ -48,51 +58,77 @@ const (
 )
 
 const (
-	defaultPrompt = "you are a code reviewer. return the response in japanese."
+	defaultPrompt = "you are a code reviewer. return the response in japanese. " +
+		"at the end, list the issues found in a ```json block holding an array of objects with \"message\" " +
+		"and optionally \"line\", \"end_line\", \"severity\" (error, warning or note) and \"rule\"."
 )
 
-func (m *model) saveReviews() tea.Cmd {
-	var reviews []reviewInfo
-	for _, review := range m.reviewList {
-		reviews = append(reviews, reviewInfo{
-			ID:     review.ID,
-			Param:  review.Param,
-			Review: review.Review,
-			State:  "finish",
-		})
-	}
-	jsonData, err := json.MarshalIndent(reviews, "", "  ")
-	if err != nil {
-		return func() tea.Msg {
-			return SendErrorMessage("Failed to save marshal reviews", err)
-		}
+// errNoStorage is returned when the review store of the project could not be opened.
+var errNoStorage = errors.New("the review store is not open")
+
+// openStorage opens the review store of the current project.
+func (m *model) openStorage() error {
+	if m.storage != nil {
+		m.storage.Close()
+		m.storage = nil
 	}
-	err = os.WriteFile(m.conf.Output, jsonData, 0644)
+	storage, err := openStorage(m.outputFile, m.stateFile, m.projectRoot, m.sourceNames())
 	if err != nil {
-		return func() tea.Msg {
-			return SendErrorMessage("Failed to save json", err)
-		}
+		return err
 	}
+	m.storage = storage
 	return nil
 }
 
+// loadReviews reads the reviews of the review store.
 func (m *model) loadReviews() (*model, tea.Cmd) {
-	data, err := os.ReadFile(m.outputFile)
-	if os.IsNotExist(err) {
-		m.reviewList = []reviewInfo{}
+	m.reviewList = []reviewInfo{}
+	if m.storage == nil {
 		return m, nil
 	}
+	reviews, err := m.storage.Load()
 	if err != nil {
 		return m, func() tea.Msg {
-			return SendErrorMessage("Failed to read reviews", err)
+			return SendErrorMessage("Failed to load reviews", err)
 		}
 	}
-	if err := json.Unmarshal(data, &m.reviewList); err != nil {
-		return m, func() tea.Msg {
-			return SendErrorMessage("Failed to unmarshal reviews", err)
+	m.reviewList = reviews
+	return m, nil
+}
+
+// storeReview saves a new version of a review and updates the review list with the
+// stored review, which also has the versions saved by other instances.
+func (m *model) storeReview(msg reviewMsg) tea.Cmd {
+	review := reviewInfo{ID: msg.id, Param: msg.param, Key: msg.key, Source: msg.source, Project: m.projectRoot}
+	index := m.getReviewIndex(msg.id)
+	if index != -1 {
+		review = m.reviewList[index]
+		review.Key = msg.key
+	}
+	stored, err := review, errNoStorage
+	if m.storage != nil {
+		stored, err = m.storage.AddVersion(review, msg.version)
+	}
+	if err != nil {
+		// The review is kept until the next load so that it can still be read
+		stored = review.addVersion(msg.version)
+	}
+	if index != -1 {
+		m.reviewList[index] = stored
+	} else {
+		m.reviewList = append(m.reviewList, stored)
+	}
+	if err != nil {
+		return func() tea.Msg {
+			return SendErrorMessage("Failed to save review", err)
 		}
 	}
-	return m, nil
+	if err := m.writeSidecar(stored); err != nil {
+		return func() tea.Msg {
+			return SendErrorMessage("Failed to write review file", err)
+		}
+	}
+	return nil
 }
 
 func (m *model) getReviewIndex(id string) int {
@@ -105,43 +141,72 @@ func (m *model) getReviewIndex(id string) int {
 }
 
 func (m *model) reviewContent() tea.Cmd {
+	selectedItem, ok := m.panels.itemListPanel.model.SelectedItem().(listItem)
+	if !ok {
+		return nil
+	}
+	return m.reviewItem(selectedItem)
+}
+
+// reviewItem requests a review of item together with the context items.
+func (m *model) reviewItem(item listItem) tea.Cmd {
 	return func() tea.Msg {
 		var (
 			chat   *openai.ChatCompletion
 			review string
+			hash   string
+			status string
+			usage  state.Usage
 			err    error
 		)
-		selectedItem, ok := m.panels.itemListPanel.model.SelectedItem().(listItem)
-
-		if ok {
-			context := m.getContextString()
-			// Generate content by including contextItems
-			content := previewContent(selectedItem, m.conf.Sources)
-			content = context + content
-			chat, err = m.client.GetReviewFromChatGPTWithPrompt(content, m.conf, m.getPrompt())
-			if err != nil {
-				review = fmt.Sprintf("Failed to get review: %v", err)
-			} else {
-				review = chat.Choices[0].Message.Content
-			}
+		prompt := m.itemPrompt(item)
+		instantPrompt := m.instantPrompt
+		context := m.getContextString()
+		// Generate content by including contextItems
+		payload := m.payloadContent(item)
+		content := context + payload
+		images := m.getContextImages()
+		image, hasImage := payloadImage(item, m.conf)
+		if hasImage {
+			images = append(images, image)
+		}
+		if len(images) != 0 {
+			chat, err = m.client.GetReviewFromChatGPTWithImages(content, images, m.conf, prompt)
+		} else {
+			chat, err = m.client.GetReviewFromChatGPTWithPrompt(content, m.conf, prompt)
+		}
+		if err != nil {
+			review = fmt.Sprintf("Failed to get review: %v", err)
+			status = reviewFailed
+		} else {
+			review = chat.Choices[0].Message.Content
+			hash = reviewPayloadHash(payload, image)
+		}
 
-			if m.instantPrompt != "" {
-				m.uiState.PromptHistory = append(m.uiState.PromptHistory, m.instantPrompt)
+		if chat != nil {
+			usage = state.Usage{
+				PromptTokens:     chat.Usage.PromptTokens,
+				CompletionTokens: chat.Usage.CompletionTokens,
 			}
-			promptToken := m.uiState.Usage.PromptTokens + chat.Usage.PromptTokens
-			completionTokens := m.uiState.Usage.CompletionTokens + chat.Usage.CompletionTokens
-			m.uiState.Usage = state.Usage{
-				PromptTokens:     promptToken,
-				CompletionTokens: completionTokens,
-			}
-			m.UpdateState()
-			state.SaveState(m.stateFile, m.uiState)
 		}
 		return reviewMsg{
-			id:      selectedItem.id,
-			content: review,
+			id:            item.id,
+			param:         item.param,
+			key:           itemKey(item),
+			source:        item.sourceName,
+			instantPrompt: instantPrompt,
+			version: reviewVersion{
+				ReviewedAt:  time.Now(),
+				Model:       m.conf.Model,
+				Prompt:      prompt,
+				ContextIDs:  m.getContextIDs(),
+				PayloadHash: hash,
+				Usage:       usage,
+				Review:      review,
+				Author:      m.author,
+				State:       status,
+			},
 		}
-
 	}
 }
 
@@ -154,12 +219,35 @@ func (m *model) getContextString() string {
 	for _, item := range items {
 		item, ok := item.(listItem)
 		if ok {
-			contextItems = append(contextItems, item.param+"\n"+previewContent(item, m.conf.Sources))
+			contextItems = append(contextItems, item.param+"\n"+m.payloadContent(item))
 		}
 	}
 	return strings.Join(contextItems, "\n\n")
 }
 
+func (m *model) getContextIDs() []string {
+	var ids []string
+	for _, item := range m.panels.contextListPanel.Items() {
+		if item, ok := item.(listItem); ok {
+			ids = append(ids, item.id)
+		}
+	}
+	return ids
+}
+
+// getContextImages returns the images of the context items sent along with the review request.
+func (m *model) getContextImages() []string {
+	var images []string
+	for _, item := range m.panels.contextListPanel.Items() {
+		if item, ok := item.(listItem); ok {
+			if image, ok := payloadImage(item, m.conf); ok {
+				images = append(images, image)
+			}
+		}
+	}
+	return images
+}
+
 func (m *model) deleteReview(reviewID string) tea.Cmd {
 	index := m.getReviewIndex(reviewID)
 	if index == -1 {
@@ -168,28 +256,38 @@ func (m *model) deleteReview(reviewID string) tea.Cmd {
 		}
 	}
 
+	if m.storage == nil {
+		return func() tea.Msg {
+			return SendErrorMessage("Failed to delete review", errNoStorage)
+		}
+	}
+	if err := m.storage.Delete(reviewID); err != nil {
+		return func() tea.Msg {
+			return SendErrorMessage("Failed to delete review", err)
+		}
+	}
+	review := m.reviewList[index]
 	m.reviewList = append(m.reviewList[:index], m.reviewList[index+1:]...)
 
-	m.saveReviews()
-	return nil
+	if err := m.removeSidecar(review); err != nil {
+		return func() tea.Msg {
+			return SendErrorMessage("Failed to remove review file", err)
+		}
+	}
+	return m.removeNoteCmd(review)
 }
 
-// getPrompt retrieves the appropriate prompt for the code review process.
+// itemPrompt retrieves the appropriate prompt for reviewing item.
 // It first checks if an instant prompt has been set by the user. If so, that prompt is returned.
-// If there's no instant prompt, it attempts to fetch the associated prompt from the selected item in the item list panel.
+// If there's no instant prompt, it attempts to fetch the associated prompt from the source of the item.
 // If an associated prompt is not found, it checks the global configuration for a default prompt.
 // If no prompts are defined either in the item source or the configuration, the function will return a predefined default prompt:
-func (m *model) getPrompt() string {
+func (m *model) itemPrompt(item listItem) string {
 	if m.instantPrompt != "" {
 		return m.instantPrompt
 	}
 
-	selectedItem, ok := m.panels.itemListPanel.model.SelectedItem().(listItem)
-	if !ok {
-		return ""
-	}
-
-	itemSource, err := getSource(selectedItem.sourceName, m.conf.Sources)
+	itemSource, err := getSource(item.sourceName, m.conf.Sources)
 	if err == nil && itemSource.Prompt != "" {
 		return itemSource.Prompt
 	}
@@ -200,3 +298,28 @@ func (m *model) getPrompt() string {
 
 	return defaultPrompt
 }
+
+// recordReviewState adds the usage of a review to the review store and its instant prompt
+// to the state file. It runs in Update, as the state is shared by the review commands.
+func (m *model) recordReviewState(msg reviewMsg) tea.Cmd {
+	if m.storage != nil {
+		if err := m.storage.AddUsage(msg.version.Usage); err != nil {
+			return func() tea.Msg {
+				return SendErrorMessage("Failed to save usage", err)
+			}
+		}
+	}
+	if msg.instantPrompt == "" {
+		return nil
+	}
+	updated, err := state.Update(m.stateFile, func(s *state.State) {
+		s.PromptHistory = append(s.PromptHistory, msg.instantPrompt)
+	})
+	if err != nil {
+		return func() tea.Msg {
+			return SendErrorMessage("Failed to save state", err)
+		}
+	}
+	m.uiState = updated
+	return nil
+}
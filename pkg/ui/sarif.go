@@ -0,0 +1,284 @@
+package ui
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+)
+
+const (
+	toolName = "lazyreview"
+	toolURL  = "https://github.com/shutils/lazyreview"
+
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifVersion = "2.1.0"
+	// Base id of artifact URIs, relative to the repository root
+	sarifRootID = "SRCROOT"
+
+	// Rule of reviews without structured findings
+	reviewRuleID = "lazyreview/review"
+	// Rule of findings that do not name one
+	findingRuleID = "lazyreview/finding"
+)
+
+// reportFinding is a finding of a report, or a file-level note holding the whole review
+// if the review has no structured findings.
+type reportFinding struct {
+	finding
+	item reportItem
+	path string // Relative to the repository root. Empty if the item is not a file
+}
+
+// reportFindings returns the findings of the reviews of items. Failed reviews have none.
+func reportFindings(items []reportItem, root string) []reportFinding {
+	var results []reportFinding
+	for _, item := range items {
+		if item.review.State == reviewFailed {
+			continue
+		}
+		itemPath, _ := repoRelativePath(item.review.Param, root)
+		findings := reviewFindings(item.review.Review)
+		if len(findings) == 0 {
+			results = append(results, reportFinding{
+				finding: finding{
+					Severity: levelNote,
+					Rule:     reviewRuleID,
+					Message:  strings.TrimSpace(item.review.Review),
+				},
+				item: item,
+				path: itemPath,
+			})
+			continue
+		}
+		for _, f := range findings {
+			path := itemPath
+			if f.Path != "" {
+				path, _ = repoRelativePath(f.Path, root)
+			}
+			if f.Rule == "" {
+				f.Rule = findingRuleID
+			}
+			results = append(results, reportFinding{finding: f, item: item, path: path})
+		}
+	}
+	return results
+}
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool               sarifTool                `json:"tool"`
+	OriginalURIBaseIDs map[string]sarifLocation `json:"originalUriBaseIds"`
+	Results            []sarifResult            `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text     string `json:"text"`
+	Markdown string `json:"markdown,omitempty"`
+}
+
+type sarifResult struct {
+	RuleID     string            `json:"ruleId"`
+	Level      string            `json:"level"`
+	Message    sarifMessage      `json:"message"`
+	Locations  []sarifLocations  `json:"locations,omitempty"`
+	Properties map[string]string `json:"properties,omitempty"`
+}
+
+type sarifLocations struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifLocation `json:"artifactLocation"`
+	Region           *sarifRegion  `json:"region,omitempty"`
+}
+
+type sarifLocation struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId,omitempty"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	EndLine     int `json:"endLine,omitempty"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
+
+// reportSARIF renders the findings of items as a SARIF 2.1.0 log.
+func reportSARIF(items []reportItem, root string) (string, error) {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{Name: toolName, InformationURI: toolURL, Rules: []sarifRule{}}},
+		OriginalURIBaseIDs: map[string]sarifLocation{
+			sarifRootID: {URI: fileURI(root)},
+		},
+		Results: []sarifResult{},
+	}
+	rules := map[string]bool{}
+	for _, f := range reportFindings(items, root) {
+		if !rules[f.Rule] {
+			rules[f.Rule] = true
+			run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{
+				ID:               f.Rule,
+				ShortDescription: sarifMessage{Text: ruleDescription(f.Rule)},
+			})
+		}
+		result := sarifResult{
+			RuleID:     f.Rule,
+			Level:      findingLevel(f.Severity),
+			Message:    sarifMessage{Text: f.Message, Markdown: f.Message},
+			Properties: findingProperties(f),
+		}
+		if f.path != "" {
+			location := sarifPhysicalLocation{ArtifactLocation: sarifLocation{URI: f.path, URIBaseID: sarifRootID}}
+			if f.Line > 0 {
+				location.Region = &sarifRegion{StartLine: f.Line, EndLine: f.EndLine, StartColumn: f.Column}
+			}
+			result.Locations = []sarifLocations{{PhysicalLocation: location}}
+		}
+		run.Results = append(run.Results, result)
+	}
+	return marshalReport(sarifLog{Schema: sarifSchema, Version: sarifVersion, Runs: []sarifRun{run}})
+}
+
+type rdjsonResult struct {
+	Source      rdjsonSource       `json:"source"`
+	Diagnostics []rdjsonDiagnostic `json:"diagnostics"`
+}
+
+type rdjsonSource struct {
+	Name string `json:"name"`
+	URL  string `json:"url,omitempty"`
+}
+
+type rdjsonDiagnostic struct {
+	Message  string          `json:"message"`
+	Location *rdjsonLocation `json:"location,omitempty"`
+	Severity string          `json:"severity"`
+	Code     rdjsonCode      `json:"code"`
+}
+
+type rdjsonLocation struct {
+	Path  string       `json:"path"`
+	Range *rdjsonRange `json:"range,omitempty"`
+}
+
+type rdjsonRange struct {
+	Start rdjsonPosition  `json:"start"`
+	End   *rdjsonPosition `json:"end,omitempty"`
+}
+
+type rdjsonPosition struct {
+	Line   int `json:"line"`
+	Column int `json:"column,omitempty"`
+}
+
+type rdjsonCode struct {
+	Value string `json:"value"`
+}
+
+// reportRDJSON renders the findings of items in the rdjson format of reviewdog.
+func reportRDJSON(items []reportItem, root string) (string, error) {
+	result := rdjsonResult{
+		Source:      rdjsonSource{Name: toolName, URL: toolURL},
+		Diagnostics: []rdjsonDiagnostic{},
+	}
+	for _, f := range reportFindings(items, root) {
+		diagnostic := rdjsonDiagnostic{
+			Message:  f.Message,
+			Severity: rdjsonSeverity(findingLevel(f.Severity)),
+			Code:     rdjsonCode{Value: f.Rule},
+		}
+		if f.path != "" {
+			diagnostic.Location = &rdjsonLocation{Path: f.path}
+			if f.Line > 0 {
+				r := &rdjsonRange{Start: rdjsonPosition{Line: f.Line, Column: f.Column}}
+				if f.EndLine > 0 {
+					r.End = &rdjsonPosition{Line: f.EndLine}
+				}
+				diagnostic.Location.Range = r
+			}
+		}
+		result.Diagnostics = append(result.Diagnostics, diagnostic)
+	}
+	return marshalReport(result)
+}
+
+func rdjsonSeverity(level string) string {
+	switch level {
+	case levelError:
+		return "ERROR"
+	case levelNote:
+		return "INFO"
+	default:
+		return "WARNING"
+	}
+}
+
+func ruleDescription(rule string) string {
+	switch rule {
+	case reviewRuleID:
+		return "Review of a file without structured findings"
+	case findingRuleID:
+		return "Finding of a review"
+	default:
+		return rule
+	}
+}
+
+// findingProperties returns the review a finding comes from, to trace it back in lazyreview.
+func findingProperties(f reportFinding) map[string]string {
+	version := latestVersion(f.item.review)
+	properties := map[string]string{
+		"itemId": f.item.review.ID,
+		"item":   f.item.review.Param,
+	}
+	if f.item.review.Source != "" {
+		properties["source"] = f.item.review.Source
+	}
+	if version.Model != "" {
+		properties["model"] = version.Model
+	}
+	if f.Severity != "" && f.Rule != reviewRuleID {
+		properties["severity"] = f.Severity
+	}
+	return properties
+}
+
+func fileURI(path string) string {
+	path = strings.ReplaceAll(path, "\\", "/")
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	if !strings.HasSuffix(path, "/") {
+		path += "/"
+	}
+	return (&url.URL{Scheme: "file", Path: path}).String()
+}
+
+func marshalReport(v any) (string, error) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data) + "\n", nil
+}
@@ -0,0 +1,149 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/shutils/lazyreview/pkg/atomicfile"
+	"github.com/shutils/lazyreview/pkg/config"
+)
+
+// Directory of sidecar review files, under the lazyreview directory of the project
+const sidecarDir = "reviews"
+
+// Directories of the sidecars of files in the project and of other items, under the directory of their source
+const (
+	sidecarFilesDir = "files"
+	sidecarItemsDir = "items"
+)
+
+// Directory of the sidecars of the unnamed source. Named sources never map to it, as safeName escapes parentheses.
+const sidecarDefaultSourceDir = "(default)"
+
+// sidecarRoot returns the directory holding the sidecar review files of the project at root.
+func sidecarRoot(root string) string {
+	return filepath.Join(root, config.RepoDirName, sidecarDir)
+}
+
+// sidecarSourceRoot returns the directory holding the sidecars of the reviews of a source,
+// so that sources reviewing the same file do not share a sidecar.
+func sidecarSourceRoot(root string, source string) string {
+	dir := sidecarDefaultSourceDir
+	if source != "" {
+		dir = safeName(source)
+	}
+	return filepath.Join(sidecarRoot(root), dir)
+}
+
+// sidecarPath returns the file mirroring review. Reviews of files in the project mirror their path,
+// and reviews of other items are named after their param.
+func sidecarPath(root string, review reviewInfo) string {
+	if path, ok := repoRelativePath(review.Param, root); ok {
+		return filepath.Join(sidecarSourceRoot(root, review.Source), sidecarFilesDir, filepath.FromSlash(path)+".md")
+	}
+	return itemSidecarPath(root, review)
+}
+
+func itemSidecarPath(root string, review reviewInfo) string {
+	return filepath.Join(sidecarSourceRoot(root, review.Source), sidecarItemsDir, safeName(review.Param)+".md")
+}
+
+// safeName returns name as a file name. Bytes other than letters, digits, '-', '_' and
+// non-leading '.' are percent-encoded, so that different names never share a file.
+func safeName(name string) string {
+	if name == "" {
+		return "%"
+	}
+	var sb strings.Builder
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9', c == '-', c == '_', c == '.' && i != 0:
+			sb.WriteByte(c)
+		default:
+			fmt.Fprintf(&sb, "%%%02X", c)
+		}
+	}
+	return sb.String()
+}
+
+// formatSidecar returns the latest review with a front matter describing how it was made.
+func formatSidecar(review reviewInfo) string {
+	version := latestVersion(review)
+	var sb strings.Builder
+	sb.WriteString("---\n")
+	for _, field := range [][2]string{
+		{"item", review.Param},
+		{"source", review.Source},
+		{"model", version.Model},
+		{"author", version.Author},
+		{"reviewed_at", formatSidecarTime(version.ReviewedAt)},
+		{"payload_hash", version.PayloadHash},
+	} {
+		if field[1] != "" {
+			fmt.Fprintf(&sb, "%s: %q\n", field[0], field[1])
+		}
+	}
+	sb.WriteString("---\n\n")
+	sb.WriteString(strings.TrimSpace(version.Review))
+	sb.WriteString("\n")
+	return sb.String()
+}
+
+func formatSidecarTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Local().Format(time.RFC3339)
+}
+
+// writeSidecar mirrors review into its sidecar file if sidecars are enabled.
+func (m *model) writeSidecar(review reviewInfo) error {
+	if !m.conf.Sidecar {
+		return nil
+	}
+	return writeSidecar(m.projectRoot, review)
+}
+
+// writeSidecar writes the latest finished version of review, so that a failed review does
+// not replace the last good one.
+func writeSidecar(root string, review reviewInfo) error {
+	review, ok := finishedReview(review)
+	if !ok {
+		return nil
+	}
+	return atomicfile.WriteFile(sidecarPath(root, review), []byte(formatSidecar(review)), 0644)
+}
+
+// removeSidecar removes the sidecar file of a deleted review, and the directories it leaves empty.
+// The file of the review may have been deleted, so both places a sidecar can be are tried.
+func (m *model) removeSidecar(review reviewInfo) error {
+	if !m.conf.Sidecar {
+		return nil
+	}
+	top := sidecarRoot(m.projectRoot)
+	paths := []string{itemSidecarPath(m.projectRoot, review)}
+	if abs, err := filepath.Abs(review.Param); err == nil {
+		if rel, err := filepath.Rel(m.projectRoot, abs); err == nil && filepath.IsLocal(rel) {
+			paths = append(paths, filepath.Join(sidecarSourceRoot(m.projectRoot, review.Source), sidecarFilesDir, rel+".md"))
+		}
+	}
+	for _, path := range paths {
+		if err := os.Remove(path); err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return err
+		}
+		for dir := filepath.Dir(path); dir != top && strings.HasPrefix(dir, top); dir = filepath.Dir(dir) {
+			// Fails on directories that still hold sidecars
+			if os.Remove(dir) != nil {
+				break
+			}
+		}
+	}
+	return nil
+}
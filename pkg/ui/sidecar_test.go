@@ -0,0 +1,94 @@
+package ui
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shutils/lazyreview/pkg/config"
+)
+
+func TestSidecarsAreNotCollected(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "main.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(root); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	conf := config.Config{Target: "."}
+	before := workspaceFingerprint(".")
+	review := reviewInfo{ID: "1", Param: "main.go"}.addVersion(reviewVersion{ReviewedAt: time.Now(), Review: "ok"})
+	if err := writeSidecar(root, review); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(root, config.RepoDirName, sidecarDir, sidecarDefaultSourceDir, sidecarFilesDir, "main.go.md")); err != nil {
+		t.Fatalf("sidecar not written: %v", err)
+	}
+
+	items, result := defaultItemCollector(context.Background(), conf, config.Source{})
+	if result.failed() {
+		t.Fatal(result.err)
+	}
+	if len(items) != 1 || items[0].(listItem).param != "main.go" {
+		t.Errorf("collected %v, want only main.go", items)
+	}
+	if after := workspaceFingerprint("."); after != before {
+		t.Errorf("writing a sidecar changed the workspace fingerprint")
+	}
+}
+
+func TestSidecarPath(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "main.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		review reviewInfo
+		want   string
+	}{
+		{"file of the unnamed source", reviewInfo{Param: filepath.Join(root, "main.go")}, "(default)/files/main.go.md"},
+		{"file of a named source", reviewInfo{Param: filepath.Join(root, "main.go"), Source: "go"}, "go/files/main.go.md"},
+		{"source named like the unnamed one", reviewInfo{Param: filepath.Join(root, "main.go"), Source: "(default)"}, "%28default%29/files/main.go.md"},
+		{"item", reviewInfo{Param: "web/api", Source: "docker ps"}, "docker%20ps/items/web%2Fapi.md"},
+		{"item with an escaped name", reviewInfo{Param: "web_2Fapi", Source: "docker ps"}, "docker%20ps/items/web_2Fapi.md"},
+		{"hidden item", reviewInfo{Param: "..", Source: "go"}, "go/items/%2E..md"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := filepath.Join(sidecarRoot(root), filepath.FromSlash(tt.want))
+			if got := sidecarPath(root, tt.review); got != want {
+				t.Errorf("sidecarPath() = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestFailedReviewKeepsSidecar(t *testing.T) {
+	root := t.TempDir()
+	review := reviewInfo{ID: "1", Param: "web"}.addVersion(reviewVersion{ReviewedAt: time.Unix(1, 0), Review: "ok"})
+	if err := writeSidecar(root, review); err != nil {
+		t.Fatal(err)
+	}
+	review = review.addVersion(reviewVersion{ReviewedAt: time.Unix(2, 0), Review: "Failed to get review", State: reviewFailed})
+	if err := writeSidecar(root, review); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(sidecarPath(root, review))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(string(data), "\nok\n") {
+		t.Errorf("sidecar = %q, want the finished review", data)
+	}
+}
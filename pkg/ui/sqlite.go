@@ -0,0 +1,322 @@
+package ui
+
+import (
+	"database/sql"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shutils/lazyreview/pkg/state"
+	_ "modernc.org/sqlite"
+)
+
+// Time SQLite waits for a lock held by another instance
+const sqliteBusyTimeout = 10 * time.Second
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS reviews (
+	id           TEXT PRIMARY KEY,
+	param        TEXT NOT NULL,
+	source       TEXT NOT NULL,
+	project      TEXT NOT NULL,
+	state        TEXT NOT NULL,
+	review       TEXT NOT NULL,
+	reviewed_at  INTEGER NOT NULL,
+	payload_hash TEXT NOT NULL,
+	item_key     TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS reviews_source ON reviews (source);
+CREATE INDEX IF NOT EXISTS reviews_state ON reviews (state);
+CREATE INDEX IF NOT EXISTS reviews_project ON reviews (project);
+CREATE INDEX IF NOT EXISTS reviews_reviewed_at ON reviews (reviewed_at);
+CREATE TABLE IF NOT EXISTS versions (
+	review_id         TEXT NOT NULL REFERENCES reviews (id) ON DELETE CASCADE,
+	reviewed_at       INTEGER NOT NULL,
+	model             TEXT NOT NULL,
+	prompt            TEXT NOT NULL,
+	context_ids       TEXT NOT NULL,
+	payload_hash      TEXT NOT NULL,
+	prompt_tokens     INTEGER NOT NULL,
+	completion_tokens INTEGER NOT NULL,
+	review            TEXT NOT NULL,
+	author            TEXT NOT NULL DEFAULT '',
+	state             TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS versions_review_id ON versions (review_id, reviewed_at);
+CREATE TABLE IF NOT EXISTS usage (
+	recorded_at       INTEGER NOT NULL,
+	prompt_tokens     INTEGER NOT NULL,
+	completion_tokens INTEGER NOT NULL
+);
+`
+
+// sqliteStorage stores reviews, their versions and usage in a SQLite database.
+type sqliteStorage struct {
+	db *sql.DB
+}
+
+func openSQLiteStorage(path string) (*sqliteStorage, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	dsn := "file:" + path +
+		"?_pragma=busy_timeout(" + strconv.FormatInt(sqliteBusyTimeout.Milliseconds(), 10) + ")" +
+		"&_pragma=journal_mode(WAL)&_pragma=foreign_keys(1)"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if err := addSQLiteColumn(db, "versions", "author", `TEXT NOT NULL DEFAULT ''`); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if err := addSQLiteColumn(db, "versions", "state", `TEXT NOT NULL DEFAULT ''`); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if err := addSQLiteColumn(db, "reviews", "item_key", `TEXT NOT NULL DEFAULT ''`); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &sqliteStorage{db: db}, nil
+}
+
+// countSQLiteReviews returns the number of reviews of the project at root in the database at path.
+// The database is opened read only, so that it is neither created nor migrated.
+func countSQLiteReviews(path string, root string) (int, error) {
+	if _, err := os.Stat(path); err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	dsn := "file:" + path + "?mode=ro" +
+		"&_pragma=busy_timeout(" + strconv.FormatInt(sqliteBusyTimeout.Milliseconds(), 10) + ")"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+	var count int
+	err = db.QueryRow(`SELECT COUNT(*) FROM reviews WHERE project = ?`, root).Scan(&count)
+	return count, err
+}
+
+// addSQLiteColumn adds a column to a table created by an older version.
+func addSQLiteColumn(db *sql.DB, table string, column string, definition string) error {
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?`, table, column).Scan(&count); err != nil {
+		return err
+	}
+	if count != 0 {
+		return nil
+	}
+	_, err := db.Exec(`ALTER TABLE ` + table + ` ADD COLUMN ` + column + ` ` + definition)
+	return err
+}
+
+func (s *sqliteStorage) Load() ([]reviewInfo, error) {
+	return s.Query(reviewQuery{})
+}
+
+// Query selects the matching reviews with the indexes of their columns,
+// then their versions in the order they were made.
+func (s *sqliteStorage) Query(q reviewQuery) ([]reviewInfo, error) {
+	where, args := q.sqlWhere()
+	rows, err := s.db.Query(`SELECT id, param, item_key, source, project, state, review, reviewed_at, payload_hash FROM reviews`+where+` ORDER BY reviewed_at`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reviews := []reviewInfo{}
+	index := map[string]int{}
+	for rows.Next() {
+		var review reviewInfo
+		var reviewedAt int64
+		if err := rows.Scan(&review.ID, &review.Param, &review.Key, &review.Source, &review.Project, &review.State, &review.Review, &reviewedAt, &review.PayloadHash); err != nil {
+			return nil, err
+		}
+		review.ReviewedAt = time.Unix(0, reviewedAt)
+		index[review.ID] = len(reviews)
+		reviews = append(reviews, review)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	versions, err := s.db.Query(`SELECT review_id, reviewed_at, model, prompt, context_ids, payload_hash, prompt_tokens, completion_tokens, review, author, state FROM versions WHERE review_id IN (SELECT id FROM reviews`+where+`) ORDER BY review_id, reviewed_at, rowid`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer versions.Close()
+	for versions.Next() {
+		var id, contextIDs string
+		var reviewedAt int64
+		var version reviewVersion
+		if err := versions.Scan(&id, &reviewedAt, &version.Model, &version.Prompt, &contextIDs, &version.PayloadHash, &version.Usage.PromptTokens, &version.Usage.CompletionTokens, &version.Review, &version.Author, &version.State); err != nil {
+			return nil, err
+		}
+		version.ReviewedAt = time.Unix(0, reviewedAt)
+		if err := json.Unmarshal([]byte(contextIDs), &version.ContextIDs); err != nil {
+			return nil, err
+		}
+		if i, ok := index[id]; ok {
+			reviews[i].History = append(reviews[i].History, version)
+		}
+	}
+	return reviews, versions.Err()
+}
+
+func (q reviewQuery) sqlWhere() (string, []any) {
+	var conds []string
+	var args []any
+	for _, c := range []struct {
+		column string
+		value  string
+	}{
+		{"id", q.ID},
+		{"source", q.Source},
+		{"state", q.State},
+		{"project", q.Project},
+	} {
+		if c.value != "" {
+			conds = append(conds, c.column+" = ?")
+			args = append(args, c.value)
+		}
+	}
+	if !q.Since.IsZero() {
+		conds = append(conds, "reviewed_at >= ?")
+		args = append(args, q.Since.UnixNano())
+	}
+	if !q.Until.IsZero() {
+		conds = append(conds, "reviewed_at < ?")
+		args = append(args, q.Until.UnixNano())
+	}
+	if len(conds) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
+
+func (s *sqliteStorage) AddVersion(review reviewInfo, version reviewVersion) (reviewInfo, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return reviewInfo{}, err
+	}
+	defer tx.Rollback()
+
+	if err := upsertReview(tx, review.addVersion(version)); err != nil {
+		return reviewInfo{}, err
+	}
+	if err := insertVersion(tx, review.ID, version); err != nil {
+		return reviewInfo{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return reviewInfo{}, err
+	}
+	stored, err := s.Query(reviewQuery{ID: review.ID})
+	if err != nil || len(stored) == 0 {
+		return reviewInfo{}, err
+	}
+	return stored[0], nil
+}
+
+// upsertReview saves the latest review of review. A review already stored is only
+// updated if review is newer.
+func upsertReview(tx *sql.Tx, review reviewInfo) error {
+	_, err := tx.Exec(`INSERT INTO reviews (id, param, item_key, source, project, state, review, reviewed_at, payload_hash)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT (id) DO UPDATE SET
+	param = excluded.param,
+	item_key = COALESCE(NULLIF(excluded.item_key, ''), reviews.item_key),
+	source = excluded.source,
+	project = excluded.project,
+	state = excluded.state,
+	review = excluded.review,
+	reviewed_at = excluded.reviewed_at,
+	payload_hash = excluded.payload_hash
+WHERE excluded.reviewed_at >= reviews.reviewed_at`,
+		review.ID, review.Param, review.Key, review.Source, review.Project, latestVersion(review).state(), review.Review, review.ReviewedAt.UnixNano(), review.PayloadHash)
+	return err
+}
+
+func insertVersion(tx *sql.Tx, id string, version reviewVersion) error {
+	contextIDs, err := json.Marshal(version.ContextIDs)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`INSERT INTO versions (review_id, reviewed_at, model, prompt, context_ids, payload_hash, prompt_tokens, completion_tokens, review, author, state)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		id, version.ReviewedAt.UnixNano(), version.Model, version.Prompt, string(contextIDs), version.PayloadHash, version.Usage.PromptTokens, version.Usage.CompletionTokens, version.Review, version.Author, version.State)
+	return err
+}
+
+func (s *sqliteStorage) Delete(id string) error {
+	_, err := s.db.Exec(`DELETE FROM reviews WHERE id = ?`, id)
+	return err
+}
+
+// Import adds the versions of the reviews that the store does not have.
+func (s *sqliteStorage) Import(reviews []reviewInfo) error {
+	stored, err := s.Load()
+	if err != nil {
+		return err
+	}
+	existing := map[string]reviewInfo{}
+	for _, review := range stored {
+		existing[review.ID] = review
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, review := range reviews {
+		old := existing[review.ID]
+		known := map[versionKey]bool{}
+		for _, version := range old.versions() {
+			known[version.key()] = true
+		}
+		merged := mergeVersions(old, review)
+		merged.ID, merged.Param, merged.Key, merged.Source, merged.Project = review.ID, review.Param, review.Key, review.Source, review.Project
+		if err := upsertReview(tx, merged); err != nil {
+			return err
+		}
+		for _, version := range review.versions() {
+			if known[version.key()] {
+				continue
+			}
+			known[version.key()] = true
+			if err := insertVersion(tx, review.ID, version); err != nil {
+				return err
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *sqliteStorage) AddUsage(usage state.Usage) error {
+	_, err := s.db.Exec(`INSERT INTO usage (recorded_at, prompt_tokens, completion_tokens) VALUES (?, ?, ?)`,
+		time.Now().UnixNano(), usage.PromptTokens, usage.CompletionTokens)
+	return err
+}
+
+func (s *sqliteStorage) TotalUsage() (state.Usage, error) {
+	var usage state.Usage
+	err := s.db.QueryRow(`SELECT COALESCE(SUM(prompt_tokens), 0), COALESCE(SUM(completion_tokens), 0) FROM usage`).
+		Scan(&usage.PromptTokens, &usage.CompletionTokens)
+	return usage, err
+}
+
+func (s *sqliteStorage) Close() error {
+	return s.db.Close()
+}
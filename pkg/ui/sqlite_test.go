@@ -0,0 +1,140 @@
+package ui
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/shutils/lazyreview/pkg/state"
+)
+
+func TestSQLiteStorage(t *testing.T) {
+	storage, err := openSQLiteStorage(filepath.Join(t.TempDir(), "reviews.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer storage.Close()
+
+	review := reviewInfo{ID: "1", Param: "main.go", Key: "main.go", Source: "go", Project: "/p"}
+	finished := reviewVersion{ReviewedAt: time.Unix(1, 0), Model: "m", PayloadHash: "h", Review: "ok", ContextIDs: []string{"2"}, Author: "alice"}
+	failed := reviewVersion{ReviewedAt: time.Unix(2, 0), Model: "m", Review: "Failed to get review", State: reviewFailed}
+	stored, err := storage.AddVersion(review, finished)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.State != reviewFinished || stored.Key != "main.go" || len(stored.History) != 1 || stored.History[0].ContextIDs[0] != "2" || stored.History[0].Author != "alice" {
+		t.Errorf("stored %+v", stored)
+	}
+	stored, err = storage.AddVersion(stored, failed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.State != reviewFailed || stored.Review != failed.Review || len(stored.History) != 2 || stored.History[1].State != reviewFailed {
+		t.Errorf("failed version stored as %+v", stored)
+	}
+	if _, err := storage.AddVersion(reviewInfo{ID: "2", Param: "util.go", Project: "/other"}, finished); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		q       reviewQuery
+		wantIDs []string
+	}{
+		{"all", reviewQuery{}, []string{"2", "1"}},
+		{"project", reviewQuery{Project: "/p"}, []string{"1"}},
+		{"source", reviewQuery{Source: "go"}, []string{"1"}},
+		{"state", reviewQuery{State: reviewFinished}, []string{"2"}},
+		{"since", reviewQuery{Since: time.Unix(2, 0)}, []string{"1"}},
+		{"until", reviewQuery{Until: time.Unix(2, 0)}, []string{"2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reviews, err := storage.Query(tt.q)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(reviews) != len(tt.wantIDs) {
+				t.Fatalf("got %d reviews, want %v", len(reviews), tt.wantIDs)
+			}
+			for i, id := range tt.wantIDs {
+				if reviews[i].ID != id {
+					t.Errorf("reviews[%d].ID = %q, want %q", i, reviews[i].ID, id)
+				}
+			}
+		})
+	}
+
+	// Versions the store has are skipped, and the newest version is shown
+	imported := reviewInfo{ID: "1", Param: "main.go", Project: "/p"}.
+		addVersion(finished).
+		addVersion(reviewVersion{ReviewedAt: time.Unix(3, 0), Review: "imported", PayloadHash: "i"})
+	if err := storage.Import([]reviewInfo{imported}); err != nil {
+		t.Fatal(err)
+	}
+	reviews, err := storage.Query(reviewQuery{ID: "1"})
+	if err != nil || len(reviews) != 1 {
+		t.Fatalf("got %d reviews, %v", len(reviews), err)
+	}
+	if reviews[0].Review != "imported" || reviews[0].State != reviewFinished || len(reviews[0].History) != 3 || reviews[0].Key != "main.go" {
+		t.Errorf("imported review = %q, %q, %d versions, key %q", reviews[0].Review, reviews[0].State, len(reviews[0].History), reviews[0].Key)
+	}
+
+	if err := storage.Delete("1"); err != nil {
+		t.Fatal(err)
+	}
+	var versions int
+	if err := storage.db.QueryRow(`SELECT COUNT(*) FROM versions WHERE review_id = '1'`).Scan(&versions); err != nil || versions != 0 {
+		t.Errorf("%d versions left after deleting their review, %v", versions, err)
+	}
+
+	for _, usage := range []state.Usage{{PromptTokens: 10, CompletionTokens: 1}, {PromptTokens: 5, CompletionTokens: 2}} {
+		if err := storage.AddUsage(usage); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if usage, err := storage.TotalUsage(); err != nil || usage != (state.Usage{PromptTokens: 15, CompletionTokens: 3}) {
+		t.Errorf("TotalUsage() = %+v, %v", usage, err)
+	}
+}
+
+func TestSQLiteStorageMigratesOlderSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "reviews.db")
+	db, err := sql.Open("sqlite", "file:"+path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The schema before authors, version states and item keys were stored
+	for _, stmt := range []string{
+		`CREATE TABLE reviews (id TEXT PRIMARY KEY, param TEXT NOT NULL, source TEXT NOT NULL, project TEXT NOT NULL,
+			state TEXT NOT NULL, review TEXT NOT NULL, reviewed_at INTEGER NOT NULL, payload_hash TEXT NOT NULL)`,
+		`CREATE TABLE versions (review_id TEXT NOT NULL REFERENCES reviews (id) ON DELETE CASCADE, reviewed_at INTEGER NOT NULL,
+			model TEXT NOT NULL, prompt TEXT NOT NULL, context_ids TEXT NOT NULL, payload_hash TEXT NOT NULL,
+			prompt_tokens INTEGER NOT NULL, completion_tokens INTEGER NOT NULL, review TEXT NOT NULL)`,
+		`INSERT INTO reviews VALUES ('1', 'main.go', '', '/p', 'finish', 'ok', 1, '')`,
+		// Reviewed with a model before payload hashes were stored
+		`INSERT INTO versions VALUES ('1', 1, 'm', '', 'null', '', 0, 0, 'ok')`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	db.Close()
+
+	storage, err := openSQLiteStorage(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer storage.Close()
+	reviews, err := storage.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reviews) != 1 || reviews[0].State != reviewFinished || latestVersion(reviews[0]).state() != reviewFinished {
+		t.Errorf("migrated reviews = %+v, want one finished review", reviews)
+	}
+	if _, err := storage.AddVersion(reviews[0], reviewVersion{ReviewedAt: time.Unix(2, 0), Review: "again", Author: "bob"}); err != nil {
+		t.Errorf("AddVersion() on a migrated database: %v", err)
+	}
+}
@@ -0,0 +1,250 @@
+package ui
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/shutils/lazyreview/pkg/config"
+)
+
+// reviewFilter limits the item list to items in a review state.
+type reviewFilter int
+
+const (
+	filterAll reviewFilter = iota
+	filterUnreviewed
+	filterStale
+	filterFresh
+)
+
+func (f reviewFilter) String() string {
+	switch f {
+	case filterUnreviewed:
+		return "unreviewed"
+	case filterStale:
+		return "stale"
+	case filterFresh:
+		return "fresh"
+	default:
+		return "all"
+	}
+}
+
+func (f reviewFilter) next() reviewFilter {
+	return (f + 1) % 4
+}
+
+// match reports whether an item with title passes the filter.
+func (f reviewFilter) match(title string) bool {
+	switch f {
+	case filterUnreviewed:
+		return strings.HasPrefix(title, unreviewedPrefix)
+	case filterStale:
+		return strings.HasPrefix(title, stalePrefix)
+	case filterFresh:
+		return strings.HasPrefix(title, reviewedPrefix)
+	default:
+		return true
+	}
+}
+
+func filterItems(items []list.Item, filter reviewFilter) []list.Item {
+	if filter == filterAll {
+		return items
+	}
+	var filtered []list.Item
+	for _, item := range items {
+		if item, ok := item.(listItem); ok && filter.match(item.title) {
+			filtered = append(filtered, item)
+		}
+	}
+	return filtered
+}
+
+const (
+	// Reviewed items that are not files are checked for changes at most this often
+	staleCheckInterval = time.Minute
+	// Number of stale items ReviewAllStale reviews at the same time
+	staleReviewWorkers = 4
+)
+
+// staleCheckedMsg carries whether the reviewed items of a source are stale.
+type staleCheckedMsg struct {
+	generation int
+	stale      map[string]bool
+	checks     map[string]staleCheck
+}
+
+// staleCheck records what the last stale check of an item saw, so that items that
+// did not change since are not checked again.
+type staleCheck struct {
+	payloadHash string    // Hash stored with the review the payload was compared with
+	modTime     time.Time // Of the file of the item, zero for items that are not files
+	size        int64
+	checkedAt   time.Time
+}
+
+// current reports whether the check still holds for a review with hash. Files are checked
+// again when they are modified, and other items once staleCheckInterval has passed.
+func (c staleCheck) current(hash string, info fs.FileInfo, now time.Time) bool {
+	if c.payloadHash != hash {
+		return false
+	}
+	if info != nil {
+		return info.ModTime().Equal(c.modTime) && info.Size() == c.size
+	}
+	return c.modTime.IsZero() && now.Sub(c.checkedAt) < staleCheckInterval
+}
+
+// payloadHash returns the hash of a payload stored with its review.
+func payloadHash(payload string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(payload)))
+}
+
+// reviewPayloadHash returns the hash of what is sent for a review of an item: its payload,
+// and the data URL of its image if it is one, as the payload of an image only summarizes it.
+func reviewPayloadHash(payload string, image string) string {
+	if image == "" {
+		return payloadHash(payload)
+	}
+	return payloadHash(payload + "\x00" + image)
+}
+
+// checkStaleCmd loads the current payload of every reviewed item and compares its hash
+// with the one stored with the review. Files reviewed before hashes were stored are stale
+// if they were modified after the review. Items of follow sources, whose payload changes
+// all the time, are not checked. Items whose check in checks is still current are skipped.
+func checkStaleCmd(ctx context.Context, conf config.Config, source config.Source, items []list.Item, reviewList []reviewInfo, checks map[string]staleCheck, root string, log *commandLog, generation int) tea.Cmd {
+	if source.Follow {
+		return nil
+	}
+	reviews := map[string]reviewInfo{}
+	for _, review := range reviewList {
+		if review.State == reviewFinished && (review.PayloadHash != "" || !review.ReviewedAt.IsZero()) {
+			reviews[review.ID] = review
+		}
+	}
+	var targets []listItem
+	previous := map[string]staleCheck{}
+	for _, item := range items {
+		if item, ok := item.(listItem); ok {
+			item.id = makeHash(root, item)
+			if _, reviewed := reviews[item.id]; reviewed {
+				targets = append(targets, item)
+				if check, ok := checks[item.id]; ok {
+					previous[item.id] = check
+				}
+			}
+		}
+	}
+	if len(targets) == 0 {
+		return nil
+	}
+	// The payload of a file is its content, unless the source has a payload command
+	filePayloads := len(source.Payload) == 0
+
+	return func() tea.Msg {
+		stale := map[string]bool{}
+		checked := map[string]staleCheck{}
+		now := time.Now()
+		for _, item := range targets {
+			if ctx.Err() != nil {
+				return nil
+			}
+			review := reviews[item.id]
+			var info fs.FileInfo
+			if filePayloads || review.PayloadHash == "" {
+				if fi, err := os.Stat(item.param); err == nil && fi.Mode().IsRegular() {
+					info = fi
+				}
+			}
+			if check, ok := previous[item.id]; ok && check.current(review.PayloadHash, info, now) {
+				continue
+			}
+			if review.PayloadHash == "" {
+				// Reviewed before payload hashes were stored
+				if info == nil {
+					continue
+				}
+				stale[item.id] = info.ModTime().After(review.ReviewedAt)
+			} else {
+				image, _ := payloadImage(item, conf)
+				stale[item.id] = reviewPayloadHash(loadPayload(ctx, item, conf, log), image) != review.PayloadHash
+			}
+			check := staleCheck{payloadHash: review.PayloadHash, checkedAt: now}
+			if info != nil {
+				check.modTime, check.size = info.ModTime(), info.Size()
+			}
+			checked[item.id] = check
+		}
+		return staleCheckedMsg{generation: generation, stale: stale, checks: checked}
+	}
+}
+
+func (m *model) handleStaleChecked(msg staleCheckedMsg) tea.Cmd {
+	if msg.generation != m.collectGeneration {
+		return nil
+	}
+	for id, stale := range msg.stale {
+		m.staleItems[id] = stale
+	}
+	for id, check := range msg.checks {
+		m.staleChecks[id] = check
+	}
+	return m.rebuildItemList()
+}
+
+// ReviewAllStale queues every item whose payload changed since it was reviewed. The queued
+// items are added to the review stack, and staleReviewWorkers of them are reviewed at a time.
+func (m *model) ReviewAllStale() (tea.Model, tea.Cmd) {
+	queued := map[string]bool{}
+	for _, item := range m.reviewQueue {
+		queued[item.id] = true
+	}
+	var cmds []tea.Cmd
+	for _, item := range m.panels.itemListPanel.model.Items() {
+		item, ok := item.(listItem)
+		if !ok || !strings.HasPrefix(item.title, stalePrefix) || queued[item.id] || m.queuedReviews[item.id] {
+			continue
+		}
+		id := item.id
+		cmds = append(cmds, func() tea.Msg {
+			return reviewStackMsg{
+				id:        id,
+				operation: Add,
+			}
+		})
+		m.reviewQueue = append(m.reviewQueue, item)
+	}
+	if len(cmds) == 0 {
+		return m, func() tea.Msg {
+			return showMessageMsg{message: "No stale items"}
+		}
+	}
+	return m, tea.Batch(append(cmds, m.startQueuedReviews())...)
+}
+
+// startQueuedReviews reviews queued items until staleReviewWorkers of them are being reviewed.
+func (m *model) startQueuedReviews() tea.Cmd {
+	var cmds []tea.Cmd
+	for len(m.queuedReviews) < staleReviewWorkers && len(m.reviewQueue) > 0 {
+		item := m.reviewQueue[0]
+		m.reviewQueue = m.reviewQueue[1:]
+		m.queuedReviews[item.id] = true
+		cmds = append(cmds, m.reviewItem(item))
+	}
+	return tea.Batch(cmds...)
+}
+
+// CycleReviewFilter switches the item list between all, unreviewed, stale and fresh items.
+func (m *model) CycleReviewFilter() (tea.Model, tea.Cmd) {
+	m.reviewFilter = m.reviewFilter.next()
+	return m, m.rebuildItemList()
+}
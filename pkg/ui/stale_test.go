@@ -0,0 +1,140 @@
+package ui
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/shutils/lazyreview/pkg/config"
+)
+
+func TestStaleCheckCurrent(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(root, "main.go")
+	if err := os.WriteFile(path, []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	file := staleCheck{payloadHash: "h", modTime: info.ModTime(), size: info.Size(), checkedAt: now.Add(-time.Hour)}
+	item := staleCheck{payloadHash: "h", checkedAt: now.Add(-staleCheckInterval / 2)}
+
+	tests := []struct {
+		name  string
+		check staleCheck
+		hash  string
+		info  os.FileInfo
+		now   time.Time
+		want  bool
+	}{
+		{"unmodified file", file, "h", info, now, true},
+		{"modified file", staleCheck{payloadHash: "h", modTime: info.ModTime().Add(-time.Second), size: info.Size()}, "h", info, now, false},
+		{"resized file", staleCheck{payloadHash: "h", modTime: info.ModTime(), size: info.Size() + 1}, "h", info, now, false},
+		{"reviewed again", file, "other", info, now, false},
+		{"file removed", file, "h", nil, now, false},
+		{"item checked recently", item, "h", nil, now, true},
+		{"item checked long ago", item, "h", nil, now.Add(staleCheckInterval), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.check.current(tt.hash, tt.info, tt.now); got != tt.want {
+				t.Errorf("current() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckStaleCmd(t *testing.T) {
+	root := t.TempDir()
+	conf := config.Config{Vision: true}
+	write := func(name string, content string) string {
+		t.Helper()
+		path := filepath.Join(root, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	hash := func(path string) string {
+		t.Helper()
+		image, _ := payloadImage(listItem{param: path}, conf)
+		return reviewPayloadHash(loadPayload(context.Background(), listItem{param: path}, conf, newCommandLog()), image)
+	}
+
+	fresh := write("fresh.go", "package fresh\n")
+	changed := write("changed.go", "package changed\n")
+	legacyChanged := write("legacy_changed.go", "package legacy\n")
+	legacyFresh := write("legacy_fresh.go", "package legacy\n")
+	failed := write("failed.go", "package failed\n")
+	cached := write("cached.go", "package cached\n")
+	image := write("image.png", "AAAA")
+
+	modTime := time.Now().Add(-time.Hour)
+	reviewedAt := modTime.Add(-time.Minute)
+	for _, path := range []string{image, legacyChanged} {
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	reviews := []reviewInfo{
+		{ID: itemID(root, "", fresh), State: reviewFinished, PayloadHash: hash(fresh)},
+		{ID: itemID(root, "", changed), State: reviewFinished, PayloadHash: hash(changed)},
+		{ID: itemID(root, "", legacyChanged), State: reviewFinished, ReviewedAt: reviewedAt},
+		{ID: itemID(root, "", legacyFresh), State: reviewFinished, ReviewedAt: time.Now().Add(time.Hour)},
+		{ID: itemID(root, "", failed), State: reviewFailed, PayloadHash: "h"},
+		{ID: itemID(root, "", cached), State: reviewFinished, PayloadHash: "h"},
+		{ID: itemID(root, "", image), State: reviewFinished, PayloadHash: hash(image)},
+	}
+	write("changed.go", "package changed // edited\n")
+	// Same size and time, so only the bytes of the image tell it changed
+	write("image.png", "BBBB")
+	if err := os.Chtimes(image, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(cached)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checks := map[string]staleCheck{
+		itemID(root, "", cached): {payloadHash: "h", modTime: info.ModTime(), size: info.Size()},
+	}
+
+	var items []list.Item
+	for _, path := range []string{fresh, changed, legacyChanged, legacyFresh, failed, cached, image} {
+		items = append(items, listItem{param: path})
+	}
+	cmd := checkStaleCmd(context.Background(), conf, config.Source{}, items, reviews, checks, root, newCommandLog(), 1)
+	msg, ok := cmd().(staleCheckedMsg)
+	if !ok {
+		t.Fatal("no stale check result")
+	}
+	want := map[string]bool{
+		fresh:         false,
+		changed:       true,
+		legacyChanged: true,
+		legacyFresh:   false,
+		image:         true,
+	}
+	if len(msg.stale) != len(want) {
+		t.Errorf("checked %d items, want %d", len(msg.stale), len(want))
+	}
+	for path, stale := range want {
+		id := itemID(root, "", path)
+		if got, ok := msg.stale[id]; !ok || got != stale {
+			t.Errorf("%s stale = %v (checked %v), want %v", filepath.Base(path), got, ok, stale)
+		}
+		if _, ok := msg.checks[id]; !ok {
+			t.Errorf("%s check not recorded", filepath.Base(path))
+		}
+	}
+
+	if cmd := checkStaleCmd(context.Background(), conf, config.Source{Follow: true}, items, reviews, nil, root, newCommandLog(), 1); cmd != nil {
+		t.Errorf("items of a follow source are checked")
+	}
+}
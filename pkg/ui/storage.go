@@ -0,0 +1,258 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/shutils/lazyreview/pkg/atomicfile"
+	"github.com/shutils/lazyreview/pkg/state"
+)
+
+// URL schemes of the review store location
+const (
+	jsonScheme   = "json://"
+	sqliteScheme = "sqlite://"
+)
+
+// reviewStorage persists reviews and token usage.
+// Reviews are only changed by appending versions, so that instances sharing a store do
+// not overwrite each other's reviews.
+type reviewStorage interface {
+	// Load returns every review in the store.
+	Load() ([]reviewInfo, error)
+	// Query returns the reviews matching q.
+	Query(q reviewQuery) ([]reviewInfo, error)
+	// AddVersion appends version to the review, creating it if needed, and returns the stored review.
+	AddVersion(review reviewInfo, version reviewVersion) (reviewInfo, error)
+	// Delete removes the review with id.
+	Delete(id string) error
+	// Import adds reviews to the store, merging the versions of reviews it already has.
+	Import(reviews []reviewInfo) error
+	// AddUsage adds the tokens used by a review to the total.
+	AddUsage(usage state.Usage) error
+	// TotalUsage returns the tokens used by all reviews.
+	TotalUsage() (state.Usage, error)
+	Close() error
+}
+
+// reviewQuery selects reviews. Zero fields match every review.
+type reviewQuery struct {
+	ID      string
+	Source  string
+	State   string
+	Project string
+	Since   time.Time // Reviewed at or after
+	Until   time.Time // Reviewed before
+}
+
+func (q reviewQuery) match(review reviewInfo) bool {
+	return (q.ID == "" || review.ID == q.ID) &&
+		(q.Source == "" || review.Source == q.Source) &&
+		(q.State == "" || review.State == q.State) &&
+		(q.Project == "" || review.Project == q.Project) &&
+		(q.Since.IsZero() || !review.ReviewedAt.Before(q.Since)) &&
+		(q.Until.IsZero() || review.ReviewedAt.Before(q.Until))
+}
+
+// openStorage opens the review store at location. Locations starting with sqlite:// are
+// SQLite databases, and other locations are JSON files, optionally prefixed with json://.
+// root and sourceNames are used to migrate JSON files written by older versions.
+func openStorage(location string, stateFile string, root string, sourceNames []string) (reviewStorage, error) {
+	if path, ok := strings.CutPrefix(location, sqliteScheme); ok {
+		return openSQLiteStorage(path)
+	}
+	path := strings.TrimPrefix(location, jsonScheme)
+	if path == "" {
+		return nil, fmt.Errorf("review store location is empty")
+	}
+	return &jsonStorage{
+		path:        path,
+		stateFile:   stateFile,
+		root:        root,
+		sourceNames: sourceNames,
+	}, nil
+}
+
+// countStoredReviews returns the number of reviews of the project at root in the store at location.
+// The store is only read: JSON files written by older versions are counted without being migrated.
+func countStoredReviews(location string, root string) (int, error) {
+	if path, ok := strings.CutPrefix(location, sqliteScheme); ok {
+		return countSQLiteReviews(path, root)
+	}
+	data, err := os.ReadFile(strings.TrimPrefix(location, jsonScheme))
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	reviews, _, err := decodeReviewStore(data, root, nil)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, review := range reviews {
+		if review.Project == root {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// jsonStorage stores reviews in a JSON file and usage in the state file.
+// Every change reads the file again under a lock and rewrites it atomically.
+type jsonStorage struct {
+	path        string
+	stateFile   string
+	root        string
+	sourceNames []string
+}
+
+// Load reads the review file, migrating it to the current schema if needed.
+// The file is backed up before it is rewritten by a migration.
+func (s *jsonStorage) Load() ([]reviewInfo, error) {
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return []reviewInfo{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	reviews, migrated, err := decodeReviewStore(data, s.root, s.sourceNames)
+	if err != nil {
+		return nil, err
+	}
+	if migrated {
+		if err := atomicfile.WriteFile(s.path+reviewStoreBackupSuffix, data, 0644); err != nil {
+			return nil, fmt.Errorf("failed to back up reviews before migration: %w", err)
+		}
+		if err := s.update(func(stored []reviewInfo) []reviewInfo { return stored }); err != nil {
+			return nil, err
+		}
+	}
+	return reviews, nil
+}
+
+func (s *jsonStorage) Query(q reviewQuery) ([]reviewInfo, error) {
+	reviews, err := s.Load()
+	if err != nil {
+		return nil, err
+	}
+	var matched []reviewInfo
+	for _, review := range reviews {
+		if q.match(review) {
+			matched = append(matched, review)
+		}
+	}
+	return matched, nil
+}
+
+func (s *jsonStorage) AddVersion(review reviewInfo, version reviewVersion) (reviewInfo, error) {
+	var stored reviewInfo
+	err := s.update(func(reviews []reviewInfo) []reviewInfo {
+		for i := range reviews {
+			if reviews[i].ID == review.ID {
+				reviews[i] = reviews[i].addVersion(version)
+				if review.Key != "" {
+					reviews[i].Key = review.Key
+				}
+				stored = reviews[i]
+				return reviews
+			}
+		}
+		stored = review.addVersion(version)
+		return append(reviews, stored)
+	})
+	return stored, err
+}
+
+func (s *jsonStorage) Delete(id string) error {
+	return s.update(func(reviews []reviewInfo) []reviewInfo {
+		var kept []reviewInfo
+		for _, review := range reviews {
+			if review.ID != id {
+				kept = append(kept, review)
+			}
+		}
+		return kept
+	})
+}
+
+func (s *jsonStorage) Import(imported []reviewInfo) error {
+	return s.update(func(reviews []reviewInfo) []reviewInfo {
+		return importReviews(reviews, imported)
+	})
+}
+
+func (s *jsonStorage) AddUsage(usage state.Usage) error {
+	_, err := state.Update(s.stateFile, func(st *state.State) {
+		st.Usage.PromptTokens += usage.PromptTokens
+		st.Usage.CompletionTokens += usage.CompletionTokens
+	})
+	return err
+}
+
+func (s *jsonStorage) TotalUsage() (state.Usage, error) {
+	return state.LoadState(s.stateFile).Usage, nil
+}
+
+func (s *jsonStorage) Close() error {
+	return nil
+}
+
+// update applies change to the reviews in the file while it is locked.
+func (s *jsonStorage) update(change func([]reviewInfo) []reviewInfo) error {
+	unlock, err := atomicfile.Lock(s.path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	reviews := []reviewInfo{}
+	data, err := os.ReadFile(s.path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err == nil {
+		if reviews, _, err = decodeReviewStore(data, s.root, s.sourceNames); err != nil {
+			return err
+		}
+	}
+	data, err = encodeReviewStore(storedReviews(change(reviews)))
+	if err != nil {
+		return err
+	}
+	return atomicfile.WriteFile(s.path, data, 0644)
+}
+
+// storedReviews returns reviews as they are written to the review file.
+func storedReviews(reviews []reviewInfo) []reviewInfo {
+	stored := []reviewInfo{}
+	for _, review := range reviews {
+		review.State = latestVersion(review).state()
+		stored = append(stored, review)
+	}
+	return stored
+}
+
+// importReviews adds imported to reviews, merging the versions of reviews with the same id.
+func importReviews(reviews []reviewInfo, imported []reviewInfo) []reviewInfo {
+	index := map[string]int{}
+	for i, review := range reviews {
+		index[review.ID] = i
+	}
+	for _, review := range imported {
+		if i, ok := index[review.ID]; ok {
+			reviews[i] = mergeVersions(reviews[i], review)
+			if reviews[i].Key == "" {
+				reviews[i].Key = review.Key
+			}
+			continue
+		}
+		index[review.ID] = len(reviews)
+		reviews = append(reviews, review)
+	}
+	return reviews
+}
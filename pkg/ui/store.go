@@ -0,0 +1,239 @@
+package ui
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/shutils/lazyreview/pkg/ignore"
+)
+
+// Schema version of the review file written by this version
+const reviewStoreSchemaVersion = 2
+
+// Suffix of the copy of a review file kept before it is migrated
+const reviewStoreBackupSuffix = ".v1.bak"
+
+// reviewStore is the format of the review file.
+type reviewStore struct {
+	SchemaVersion int          `json:"schemaVersion"`
+	Reviews       []reviewInfo `json:"reviews"`
+}
+
+// projectRoot returns the root of the git repository containing target,
+// or the absolute path of target if it is not in a repository.
+func projectRoot(target string) string {
+	if root := ignore.RepoRoot(target); root != "" {
+		return root
+	}
+	abs, err := filepath.Abs(target)
+	if err != nil {
+		return target
+	}
+	return abs
+}
+
+// itemID returns the id of an item identified by key within a source of a project.
+func itemID(root string, sourceName string, key string) string {
+	h := sha256.New()
+	for _, part := range []string{root, sourceName, key} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// makeHash returns the id of a collected item. Items are identified by the id given
+// by their collector, by their path relative to the project root if they are files,
+// or by their param, so that the id does not depend on the working directory.
+func makeHash(root string, item listItem) string {
+	return itemID(root, item.sourceName, itemKey(item))
+}
+
+// itemKey returns the key the id of a collected item is made from.
+func itemKey(item listItem) string {
+	switch {
+	case item.key != "":
+		return item.key
+	case item.path != "":
+		return item.path
+	}
+	return item.param
+}
+
+// resolveItemParams makes the relative params of collected items absolute if they are files
+// in dir, the directory the collector ran in, so that they are read from there.
+func resolveItemParams(items []list.Item, dir string) {
+	if dir == "" {
+		return
+	}
+	for i, item := range items {
+		item, ok := item.(listItem)
+		if !ok || item.param == "" || filepath.IsAbs(item.param) {
+			continue
+		}
+		param := filepath.Join(dir, item.param)
+		if _, err := os.Stat(param); err == nil {
+			item.param = param
+			items[i] = item
+		}
+	}
+}
+
+// setItemPaths sets the path relative to root of the collected items that are files in root.
+// Items with a collector id are identified by it and are left alone.
+func setItemPaths(items []list.Item, root string) {
+	for i, item := range items {
+		item, ok := item.(listItem)
+		if !ok || item.key != "" {
+			continue
+		}
+		if path, ok := repoRelativePath(item.param, root); ok {
+			item.path = path
+			items[i] = item
+		}
+	}
+}
+
+// decodeReviewStore reads a review file and reports whether it was migrated from an older schema.
+// Files written before the schema version was introduced hold a plain array of reviews whose ids
+// are the hex encoding of the item key followed by the source name. Their ids are converted
+// using root and the names of the configured sources.
+func decodeReviewStore(data []byte, root string, sourceNames []string) ([]reviewInfo, bool, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return []reviewInfo{}, false, nil
+	}
+
+	if data[0] == '[' {
+		var reviews []reviewInfo
+		if err := json.Unmarshal(data, &reviews); err != nil {
+			return nil, false, err
+		}
+		return migrateLegacyReviews(reviews, root, sourceNames), true, nil
+	}
+
+	var store reviewStore
+	if err := json.Unmarshal(data, &store); err != nil {
+		return nil, false, err
+	}
+	if store.SchemaVersion > reviewStoreSchemaVersion {
+		return nil, false, fmt.Errorf("schema version %d is newer than the supported version %d", store.SchemaVersion, reviewStoreSchemaVersion)
+	}
+	if store.Reviews == nil {
+		store.Reviews = []reviewInfo{}
+	}
+	return store.Reviews, false, nil
+}
+
+func encodeReviewStore(reviews []reviewInfo) ([]byte, error) {
+	return json.MarshalIndent(reviewStore{
+		SchemaVersion: reviewStoreSchemaVersion,
+		Reviews:       reviews,
+	}, "", "  ")
+}
+
+func migrateLegacyReviews(reviews []reviewInfo, root string, sourceNames []string) []reviewInfo {
+	ids := map[string]string{}
+	for i, review := range reviews {
+		sourceName, key, ok := splitLegacyID(review, sourceNames)
+		if !ok {
+			continue
+		}
+		if key == review.Param {
+			if path, ok := repoRelativePath(key, root); ok {
+				key = path
+			}
+		}
+		id := itemID(root, sourceName, key)
+		ids[review.ID] = id
+		reviews[i].ID = id
+		reviews[i].Key = key
+		reviews[i].Source = sourceName
+		reviews[i].Project = root
+	}
+	for i := range reviews {
+		for j, version := range reviews[i].History {
+			for k, contextID := range version.ContextIDs {
+				if id, ok := ids[contextID]; ok {
+					reviews[i].History[j].ContextIDs[k] = id
+				}
+			}
+		}
+	}
+	return reviews
+}
+
+// splitLegacyID recovers the source name and item key from a legacy id.
+// A source whose name leaves the param of the review as the key is preferred,
+// then the longest matching source name.
+func splitLegacyID(review reviewInfo, sourceNames []string) (string, string, bool) {
+	decoded, err := hex.DecodeString(review.ID)
+	if err != nil {
+		return "", "", false
+	}
+	seed := string(decoded)
+	if seed == review.Param {
+		return "", review.Param, true
+	}
+
+	sourceName := ""
+	for _, name := range sourceNames {
+		if name == "" || !strings.HasSuffix(seed, name) {
+			continue
+		}
+		if strings.TrimSuffix(seed, name) == review.Param {
+			return name, review.Param, true
+		}
+		if len(name) > len(sourceName) {
+			sourceName = name
+		}
+	}
+	return sourceName, strings.TrimSuffix(seed, sourceName), true
+}
+
+// mergeVersions returns review with the versions of other it does not have.
+func mergeVersions(review reviewInfo, other reviewInfo) reviewInfo {
+	versions := review.versions()
+	known := map[versionKey]bool{}
+	for _, version := range versions {
+		known[version.key()] = true
+	}
+	added := false
+	for _, version := range other.versions() {
+		if !known[version.key()] {
+			versions = append(versions, version)
+			added = true
+		}
+	}
+	if !added {
+		return review
+	}
+	sort.SliceStable(versions, func(i, j int) bool {
+		return versions[i].ReviewedAt.Before(versions[j].ReviewedAt)
+	})
+	latest := versions[len(versions)-1]
+	review.History = versions
+	review.Review = latest.Review
+	review.ReviewedAt = latest.ReviewedAt
+	review.PayloadHash = latest.PayloadHash
+	review.State = latest.state()
+	return review
+}
+
+// versionKey identifies a version of a review across stores.
+type versionKey struct {
+	reviewedAt int64
+	review     string
+}
+
+func (v reviewVersion) key() versionKey {
+	return versionKey{v.ReviewedAt.UnixNano(), v.Review}
+}
@@ -0,0 +1,207 @@
+package ui
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/bubbles/list"
+)
+
+func legacyID(key string, sourceName string) string {
+	return hex.EncodeToString([]byte(key + sourceName))
+}
+
+func TestSplitLegacyID(t *testing.T) {
+	tests := []struct {
+		name        string
+		id          string
+		param       string
+		sourceNames []string
+		wantSource  string
+		wantKey     string
+		wantOK      bool
+	}{
+		{"default source", legacyID("main.go", ""), "main.go", []string{"go"}, "", "main.go", true},
+		{"named source", legacyID("main.go", "go files"), "main.go", []string{"go files"}, "go files", "main.go", true},
+		{"source leaving the param is preferred", legacyID("main.go", "go"), "main.go", []string{"o", "go"}, "go", "main.go", true},
+		{"source name ending the param", legacyID("main.go", "go"), "main.go", []string{"go", "main.gogo"}, "go", "main.go", true},
+		{"collector id with the longest source", legacyID("abc123", "docker"), "web", []string{"er", "docker"}, "docker", "abc123", true},
+		{"removed source", legacyID("abc123", "docker"), "web", []string{"go"}, "", "abc123docker", true},
+		{"not hex", "zz", "main.go", nil, "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source, key, ok := splitLegacyID(reviewInfo{ID: tt.id, Param: tt.param}, tt.sourceNames)
+			if source != tt.wantSource || key != tt.wantKey || ok != tt.wantOK {
+				t.Errorf("splitLegacyID() = %q, %q, %v, want %q, %q, %v", source, key, ok, tt.wantSource, tt.wantKey, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestDecodeReviewStore(t *testing.T) {
+	current, err := encodeReviewStore([]reviewInfo{{ID: "a", Param: "a.go", Review: "ok"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name         string
+		data         string
+		wantIDs      []string
+		wantMigrated bool
+		wantErr      bool
+	}{
+		{"empty", "  \n", nil, false, false},
+		{"current schema", string(current), []string{"a"}, false, false},
+		{"legacy array", `[{"id":"` + legacyID("b.go", "") + `","param":"b.go","review":"ok"}]`, []string{itemID("/p", "", "b.go")}, true, false},
+		{"newer schema", `{"schemaVersion":99,"reviews":[]}`, nil, false, true},
+		{"invalid", `{`, nil, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reviews, migrated, err := decodeReviewStore([]byte(tt.data), "/p", nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			if migrated != tt.wantMigrated {
+				t.Errorf("migrated = %v, want %v", migrated, tt.wantMigrated)
+			}
+			if len(reviews) != len(tt.wantIDs) {
+				t.Fatalf("got %d reviews, want %d", len(reviews), len(tt.wantIDs))
+			}
+			for i, id := range tt.wantIDs {
+				if reviews[i].ID != id {
+					t.Errorf("reviews[%d].ID = %q, want %q", i, reviews[i].ID, id)
+				}
+			}
+		})
+	}
+}
+
+func TestJSONStorageMigratesLegacyFile(t *testing.T) {
+	const root = "/project"
+	path := filepath.Join(t.TempDir(), "reviews.json")
+	contextID := legacyID("util.go", "")
+	legacy, err := json.Marshal([]reviewInfo{
+		{ID: contextID, Param: "util.go", Review: "util", State: reviewFinished},
+		{
+			ID:     legacyID("abc", "containers"),
+			Param:  "web",
+			Review: "web",
+			State:  reviewFinished,
+			History: []reviewVersion{{
+				ReviewedAt: time.Unix(1, 0),
+				ContextIDs: []string{contextID, "unknown"},
+				Review:     "web",
+			}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, legacy, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	storage := &jsonStorage{path: path, root: root, sourceNames: []string{"containers"}}
+	reviews, err := storage.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		id, source string
+	}{
+		{itemID(root, "", "util.go"), ""},
+		{itemID(root, "containers", "abc"), "containers"},
+	}
+	if len(reviews) != len(want) {
+		t.Fatalf("got %d reviews, want %d", len(reviews), len(want))
+	}
+	for i, w := range want {
+		if reviews[i].ID != w.id || reviews[i].Source != w.source || reviews[i].Project != root {
+			t.Errorf("reviews[%d] = %q, %q, %q, want %q, %q, %q", i, reviews[i].ID, reviews[i].Source, reviews[i].Project, w.id, w.source, root)
+		}
+	}
+	if got := reviews[1].History[0].ContextIDs; len(got) != 2 || got[0] != want[0].id || got[1] != "unknown" {
+		t.Errorf("context ids = %v, want [%s unknown]", got, want[0].id)
+	}
+
+	backup, err := os.ReadFile(path + reviewStoreBackupSuffix)
+	if err != nil {
+		t.Fatalf("no backup: %v", err)
+	}
+	if !bytes.Equal(backup, legacy) {
+		t.Errorf("backup differs from the legacy file")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, migrated, err := decodeReviewStore(data, root, nil); err != nil || migrated {
+		t.Errorf("migrated file was not rewritten in the current schema: migrated %v, err %v", migrated, err)
+	}
+
+	// Loading the migrated file keeps the ids and leaves the backup alone
+	if err := os.Remove(path + reviewStoreBackupSuffix); err != nil {
+		t.Fatal(err)
+	}
+	again, err := storage.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(again) != len(want) || again[0].ID != want[0].id || again[1].ID != want[1].id {
+		t.Errorf("reloaded ids changed")
+	}
+	if _, err := os.Stat(path + reviewStoreBackupSuffix); !os.IsNotExist(err) {
+		t.Errorf("backup written again for a migrated file")
+	}
+}
+
+func TestMakeHashIsRelativeToRoot(t *testing.T) {
+	root, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(root, "pkg"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "pkg", "main.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	want := itemID(root, "", "pkg/main.go")
+	tests := []struct {
+		name string
+		dir  string
+		item listItem
+		want string
+	}{
+		{"from the root", root, listItem{param: "pkg/main.go"}, want},
+		{"from a subdirectory", filepath.Join(root, "pkg"), listItem{param: "main.go"}, want},
+		{"absolute param", wd, listItem{param: filepath.Join(root, "pkg", "main.go")}, want},
+		{"collector id", root, listItem{param: "pkg/main.go", key: "abc"}, itemID(root, "", "abc")},
+		{"not a file", root, listItem{param: "web"}, itemID(root, "", "web")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := os.Chdir(tt.dir); err != nil {
+				t.Fatal(err)
+			}
+			items := []list.Item{tt.item}
+			setItemPaths(items, root)
+			if got := makeHash(root, items[0].(listItem)); got != tt.want {
+				t.Errorf("makeHash() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,331 @@
+package ui
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/shutils/lazyreview/pkg/config"
+)
+
+const (
+	defaultMaxPreviewSize = 1 << 20
+	defaultMaxPayloadSize = 256 << 10
+	// Number of leading bytes inspected to tell text from binary
+	sniffSize = 8000
+)
+
+type textEncoding int
+
+const (
+	encodingUTF8 textEncoding = iota
+	encodingUTF16LE
+	encodingUTF16BE
+	encodingBinary
+)
+
+// textFile is the decoded content of a file read by readTextFile.
+type textFile struct {
+	text        string
+	info        fs.FileInfo
+	binary      bool
+	contentType string
+	omitted     int64 // Bytes left out between the head and the tail
+	numbering   lineNumbering
+}
+
+// lineNumbering maps the lines of a text to the lines of the file it was read from.
+// The zero value numbers lines sequentially.
+type lineNumbering struct {
+	headLines int // Lines of the head before the omission marker, if the file was truncated
+	tailLine  int // Line number in the file of the first line after the marker
+}
+
+// number returns the line number in the file of the line at index i of the text,
+// and false for the omission marker.
+func (n lineNumbering) number(i int) (int, bool) {
+	switch {
+	case n.tailLine == 0 || i < n.headLines:
+		return i + 1, true
+	case i == n.headLines:
+		return 0, false
+	default:
+		return n.tailLine + i - n.headLines - 1, true
+	}
+}
+
+func maxPreviewSize(conf config.Config) int64 {
+	if conf.MaxPreviewSize > 0 {
+		return conf.MaxPreviewSize
+	}
+	return defaultMaxPreviewSize
+}
+
+func maxPayloadSize(conf config.Config) int64 {
+	if conf.MaxPayloadSize > 0 {
+		return conf.MaxPayloadSize
+	}
+	return defaultMaxPayloadSize
+}
+
+// readTextFile reads and decodes the file at path.
+// Files larger than maxSize are truncated to whole lines from their head and tail,
+// joined by a marker telling which lines were left out. The lines of the tail keep
+// their line numbers in the file, so that previews and payloads of different sizes
+// refer to the same lines.
+func readTextFile(path string, maxSize int64) (textFile, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return textFile{}, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return textFile{}, err
+	}
+	if info.IsDir() {
+		return textFile{}, errors.New(path + " is a directory")
+	}
+
+	size := info.Size()
+	var head, tail []byte
+	if size <= maxSize {
+		head, err = io.ReadAll(file)
+	} else {
+		headSize := maxSize / 2
+		head, err = readAt(file, 0, headSize)
+		if err == nil {
+			tail, err = readAt(file, size-(maxSize-headSize), maxSize-headSize)
+		}
+	}
+	if err != nil {
+		return textFile{}, err
+	}
+
+	tf := textFile{info: info}
+	encoding, bomSize := detectEncoding(head)
+	if encoding == encodingBinary {
+		tf.binary = true
+		tf.contentType = http.DetectContentType(head)
+		return tf, nil
+	}
+
+	headText := decodeText(head[bomSize:], encoding)
+	if tail == nil {
+		tf.text = headText
+		return tf, nil
+	}
+
+	// UTF-16 code units are two bytes long, so the tail has to start on an even offset
+	if encoding != encodingUTF8 && (size-int64(len(tail)))%2 != 0 {
+		tail = tail[1:]
+	}
+	tailStart := size - int64(len(tail))
+	tailText := decodeText(tail, encoding)
+	if i := strings.LastIndex(headText, "\n"); i != -1 {
+		headText = headText[:i+1]
+	}
+	newlines, err := countNewlines(file, tailStart, encoding)
+	if err != nil {
+		return textFile{}, err
+	}
+	// The tail starts on line newlines+1, which is cut off up to its end
+	tailLine := newlines + 1
+	if i := strings.Index(tailText, "\n"); i != -1 {
+		tailText = tailText[i+1:]
+		tailLine++
+	}
+	tf.omitted = size - maxSize
+	tf.numbering = lineNumbering{headLines: strings.Count(headText, "\n"), tailLine: tailLine}
+	tf.text = headText + omissionMarker(tf.numbering, size, maxSize) + tailText
+	return tf, nil
+}
+
+// truncateText truncates text longer than maxSize bytes to whole lines from its head and tail
+// like readTextFile does with files.
+func truncateText(text string, maxSize int64) (string, lineNumbering) {
+	size := int64(len(text))
+	if size <= maxSize {
+		return text, lineNumbering{}
+	}
+	headSize := maxSize / 2
+	tailStart := size - (maxSize - headSize)
+	headText := text[:headSize]
+	if i := strings.LastIndex(headText, "\n"); i != -1 {
+		headText = headText[:i+1]
+	}
+	tailText := text[tailStart:]
+	tailLine := strings.Count(text[:tailStart], "\n") + 1
+	if i := strings.Index(tailText, "\n"); i != -1 {
+		tailText = tailText[i+1:]
+		tailLine++
+	}
+	numbering := lineNumbering{headLines: strings.Count(headText, "\n"), tailLine: tailLine}
+	headText = strings.ToValidUTF8(headText, string(utf8.RuneError))
+	tailText = strings.ToValidUTF8(tailText, string(utf8.RuneError))
+	return headText + omissionMarker(numbering, size, maxSize) + tailText, numbering
+}
+
+// omissionMarker returns the line standing for the lines left out of a truncated text.
+func omissionMarker(numbering lineNumbering, size int64, maxSize int64) string {
+	return fmt.Sprintf("[... lines %d-%d omitted (%s of %s, the size limit is %s) ...]\n",
+		numbering.headLines+1, numbering.tailLine-1, formatSize(size-maxSize), formatSize(size), formatSize(maxSize))
+}
+
+// countNewlines counts the line feeds in the first size bytes of file.
+func countNewlines(file *os.File, size int64, encoding textEncoding) (int, error) {
+	// Even, so that UTF-16 code units are not split between reads
+	buf := make([]byte, 64<<10)
+	reader := io.NewSectionReader(file, 0, size)
+	count := 0
+	for {
+		n, err := io.ReadFull(reader, buf)
+		chunk := buf[:n]
+		switch encoding {
+		case encodingUTF16LE, encodingUTF16BE:
+			for i := 0; i+1 < len(chunk); i += 2 {
+				if encoding == encodingUTF16LE && chunk[i] == '\n' && chunk[i+1] == 0 ||
+					encoding == encodingUTF16BE && chunk[i] == 0 && chunk[i+1] == '\n' {
+					count++
+				}
+			}
+		default:
+			count += bytes.Count(chunk, []byte{'\n'})
+		}
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return count, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+}
+
+func readAt(file *os.File, offset int64, size int64) ([]byte, error) {
+	buf := make([]byte, size)
+	n, err := file.ReadAt(buf, offset)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	return buf[:n], nil
+}
+
+// detectEncoding guesses the encoding of content from its byte order mark or,
+// without one, from the placement of NUL bytes and the share of control characters.
+// It also returns the length of the byte order mark.
+func detectEncoding(content []byte) (textEncoding, int) {
+	switch {
+	case bytes.HasPrefix(content, []byte{0xEF, 0xBB, 0xBF}):
+		return encodingUTF8, 3
+	case bytes.HasPrefix(content, []byte{0xFF, 0xFE}):
+		return encodingUTF16LE, 2
+	case bytes.HasPrefix(content, []byte{0xFE, 0xFF}):
+		return encodingUTF16BE, 2
+	}
+
+	sample := content
+	if len(sample) > sniffSize {
+		sample = sample[:sniffSize]
+	}
+	if bytes.IndexByte(sample, 0) != -1 {
+		return detectUTF16(sample), 0
+	}
+
+	suspicious := 0
+	for _, b := range sample {
+		if b < 0x20 && b != '\t' && b != '\n' && b != '\r' && b != '\f' && b != '\b' && b != 0x1b {
+			suspicious++
+		}
+	}
+	if len(sample) > 0 && suspicious*10 > len(sample) {
+		return encodingBinary, 0
+	}
+	return encodingUTF8, 0
+}
+
+// detectUTF16 recognizes UTF-16 text without a byte order mark, which is mostly ASCII
+// with every other byte being NUL. Anything else containing NUL is binary.
+func detectUTF16(sample []byte) textEncoding {
+	var evenNul, oddNul int
+	for i, b := range sample {
+		if b != 0 {
+			continue
+		}
+		if i%2 == 0 {
+			evenNul++
+		} else {
+			oddNul++
+		}
+	}
+	units := len(sample) / 2
+	switch {
+	case evenNul == 0 && oddNul*2 > units:
+		return encodingUTF16LE
+	case oddNul == 0 && evenNul*2 > units:
+		return encodingUTF16BE
+	default:
+		return encodingBinary
+	}
+}
+
+// decodeText converts content to a UTF-8 string. Invalid sequences, such as a character
+// cut in half by truncation, are replaced with U+FFFD.
+func decodeText(content []byte, encoding textEncoding) string {
+	switch encoding {
+	case encodingUTF16LE, encodingUTF16BE:
+		units := make([]uint16, len(content)/2)
+		for i := range units {
+			if encoding == encodingUTF16LE {
+				units[i] = uint16(content[2*i]) | uint16(content[2*i+1])<<8
+			} else {
+				units[i] = uint16(content[2*i])<<8 | uint16(content[2*i+1])
+			}
+		}
+		return string(utf16.Decode(units))
+	default:
+		if utf8.Valid(content) {
+			return string(content)
+		}
+		return strings.ToValidUTF8(string(content), string(utf8.RuneError))
+	}
+}
+
+// fileSummary describes a file whose content cannot be shown as text.
+func fileSummary(path string, tf textFile) string {
+	return fmt.Sprintf(
+		"%s\n\n"+
+			"Path: %s\n"+
+			"Size: %s (%d bytes)\n"+
+			"Type: %s\n"+
+			"Mode: %s\n"+
+			"Modified: %s",
+		notTextFallback,
+		path,
+		formatSize(tf.info.Size()),
+		tf.info.Size(),
+		tf.contentType,
+		tf.info.Mode(),
+		tf.info.ModTime().Format("2006-01-02 15:04:05"),
+	)
+}
+
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,127 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+func utf16Bytes(text string, bigEndian bool) []byte {
+	var b []byte
+	for _, u := range utf16.Encode([]rune(text)) {
+		if bigEndian {
+			b = append(b, byte(u>>8), byte(u))
+		} else {
+			b = append(b, byte(u), byte(u>>8))
+		}
+	}
+	return b
+}
+
+func TestDetectEncoding(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     []byte
+		want        textEncoding
+		wantBOMSize int
+	}{
+		{"empty", nil, encodingUTF8, 0},
+		{"ascii", []byte("package main\n\tfunc main() {}\n"), encodingUTF8, 0},
+		{"utf-8", []byte("こんにちは\n"), encodingUTF8, 0},
+		{"utf-8 with bom", append([]byte{0xEF, 0xBB, 0xBF}, "a"...), encodingUTF8, 3},
+		{"utf-16le with bom", append([]byte{0xFF, 0xFE}, utf16Bytes("a", false)...), encodingUTF16LE, 2},
+		{"utf-16be with bom", append([]byte{0xFE, 0xFF}, utf16Bytes("a", true)...), encodingUTF16BE, 2},
+		{"utf-16le", utf16Bytes("hello world\n", false), encodingUTF16LE, 0},
+		{"utf-16be", utf16Bytes("hello world\n", true), encodingUTF16BE, 0},
+		{"nul bytes", []byte{0x89, 'P', 'N', 'G', 0, 0, 0, 0x0d, 'I', 'H', 'D', 'R'}, encodingBinary, 0},
+		{"control characters", []byte{1, 2, 3, 4, 'a', 5, 6, 7}, encodingBinary, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, bomSize := detectEncoding(tt.content)
+			if got != tt.want || bomSize != tt.wantBOMSize {
+				t.Errorf("detectEncoding() = %v, %d, want %v, %d", got, bomSize, tt.want, tt.wantBOMSize)
+			}
+		})
+	}
+}
+
+func TestReadTextFile(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, content []byte) string {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, content, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	tf, err := readTextFile(write("utf16.txt", append([]byte{0xFF, 0xFE}, utf16Bytes("héllo\n", false)...)), 1<<10)
+	if err != nil || tf.binary || tf.text != "héllo\n" {
+		t.Errorf("UTF-16 file read as %q (binary %v, err %v)", tf.text, tf.binary, err)
+	}
+
+	tf, err = readTextFile(write("image.png", []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\x0dIHDR")), 1<<10)
+	if err != nil || !tf.binary || tf.contentType != "image/png" {
+		t.Errorf("binary file read as binary %v, type %q, err %v", tf.binary, tf.contentType, err)
+	}
+
+	if _, err := readTextFile(dir, 1<<10); err == nil {
+		t.Errorf("directory read without an error")
+	}
+
+	var lines []string
+	for i := 1; i <= 100; i++ {
+		lines = append(lines, fmt.Sprintf("line %d", i))
+	}
+	text := strings.Join(lines, "\n") + "\n"
+	for _, encoding := range []string{"utf-8", "utf-16le"} {
+		t.Run("truncated "+encoding, func(t *testing.T) {
+			content, maxSize := []byte(text), int64(100)
+			if encoding == "utf-16le" {
+				content, maxSize = append([]byte{0xFF, 0xFE}, utf16Bytes(text, false)...), 201
+			}
+			tf, err := readTextFile(write(encoding+".txt", content), maxSize)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkTruncated(t, tf.text, tf.numbering)
+			if tf.omitted != int64(len(content))-maxSize {
+				t.Errorf("omitted = %d, want %d", tf.omitted, int64(len(content))-maxSize)
+			}
+		})
+	}
+
+	t.Run("truncated text", func(t *testing.T) {
+		truncated, numbering := truncateText(text, 100)
+		checkTruncated(t, truncated, numbering)
+		if short, numbering := truncateText("line 1\n", 100); short != "line 1\n" || numbering != (lineNumbering{}) {
+			t.Errorf("short text truncated to %q", short)
+		}
+	})
+}
+
+// checkTruncated checks that every line of a truncated text of "line N" lines is numbered N.
+func checkTruncated(t *testing.T, text string, numbering lineNumbering) {
+	t.Helper()
+	if numbering.tailLine == 0 {
+		t.Fatalf("text was not truncated: %q", text)
+	}
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	for i, line := range lines {
+		n, ok := numbering.number(i)
+		if !ok {
+			if !strings.HasPrefix(line, "[... lines ") {
+				t.Errorf("line %d = %q, want the omission marker", i, line)
+			}
+			continue
+		}
+		if want := fmt.Sprintf("line %d", n); line != want {
+			t.Errorf("line %d = %q, want %q", i, line, want)
+		}
+	}
+}
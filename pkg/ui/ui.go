@@ -1,7 +1,9 @@
 package ui
 
 import (
+	"context"
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/charmbracelet/bubbles/list"
@@ -23,12 +25,52 @@ type winSize struct {
 
 type listItem struct {
 	title, param, sourceName, id string
+	key                          string // Id given by the collector, which id is made from
+	path                         string // Slash separated path relative to the project root, if the item is a file in it
+	description, group           string
+	meta                         map[string]any
 }
 
-func (i listItem) Title() string       { return i.title }
-func (i listItem) Description() string { return i.param }
+func (i listItem) Title() string { return i.title }
+func (i listItem) Description() string {
+	desc := i.param
+	if i.description != "" {
+		desc = i.description
+	}
+	if i.group != "" {
+		desc = "[" + i.group + "] " + desc
+	}
+	if meta := i.metaText(); meta != "" {
+		desc += " · " + meta
+	}
+	return desc
+}
+
+// metaText returns the meta fields of the item as key=value pairs sorted by key.
+func (i listItem) metaText() string {
+	keys := make([]string, 0, len(i.meta))
+	for key := range i.meta {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, len(keys))
+	for j, key := range keys {
+		pairs[j] = fmt.Sprintf("%s=%v", key, i.meta[key])
+	}
+	return strings.Join(pairs, " ")
+}
 func (i listItem) FilterValue() string { return i.param }
 
+// plainTitle returns the title without the review state prefix.
+func (i listItem) plainTitle() string {
+	for _, prefix := range []string{reviewedPrefix, unreviewedPrefix, stalePrefix} {
+		if strings.HasPrefix(i.title, prefix) {
+			return strings.TrimPrefix(i.title, prefix)
+		}
+	}
+	return i.title
+}
+
 type updateSourceListMsg struct {
 }
 
@@ -54,6 +96,33 @@ type model struct {
 	state                  state.State
 	message                string
 	initialized            bool
+	commandLog             *commandLog
+	sourceErrors           map[string]commandResult
+	collectedItems         map[string][]list.Item // Collected items by source name
+	loadingSources         map[string]bool
+	collectGeneration      int
+	collectCtx             context.Context
+	collectCancel          context.CancelFunc
+	initCmd                tea.Cmd
+	watchFingerprints      map[string]string
+	watchEvents            chan string
+	watchStop              chan struct{} // Closed to stop the notify watchers
+	watchGeneration        int
+	pendingSelectID        string
+	previewCache           *previewCache
+	previewCancel          context.CancelFunc
+	previewSeq             int
+	showPayload            bool
+	follow                 *followProcess
+	staleItems             map[string]bool // Whether the payload of a reviewed item changed, by item id
+	staleChecks            map[string]staleCheck
+	reviewQueue            []listItem      // Items waiting to be reviewed by ReviewAllStale
+	queuedReviews          map[string]bool // Ids of the queued items being reviewed
+	reviewFilter           reviewFilter
+	reviewHistory          reviewHistoryView
+	projectRoot            string // Part of the item ids
+	author                 string // Author of the reviews made in this instance
+	storage                reviewStorage
 }
 
 func NewUi(conf config.Config, client openai.Client) model {
@@ -62,7 +131,7 @@ func NewUi(conf config.Config, client openai.Client) model {
 		keyMaps:             DefaultKeyMap(),
 		reviewList:          []reviewInfo{},
 		targetDir:           conf.Target,
-		outputFile:          conf.Output,
+		outputFile:          conf.ReviewStorePath(projectRoot(conf.Target)),
 		stateFile:           conf.State,
 		conf:                conf,
 		client:              client,
@@ -75,21 +144,30 @@ func NewUi(conf config.Config, client openai.Client) model {
 		state:               state.State{},
 		message:             "",
 		initialized:         true,
+		commandLog:          newCommandLog(),
+		sourceErrors:        map[string]commandResult{},
+		collectedItems:      map[string][]list.Item{},
+		loadingSources:      map[string]bool{},
+		watchFingerprints:   map[string]string{},
+		previewCache:        newPreviewCache(),
+		staleItems:          map[string]bool{},
+		staleChecks:         map[string]staleCheck{},
+		queuedReviews:       map[string]bool{},
+		projectRoot:         projectRoot(conf.Target),
 	}
 	m.panels.configDetailPanel.SetContent(strings.Join(conf.ToStringArray(), "\n"))
 	m.panels.configSummaryPanel.SetContent("Config path: " + conf.ConfigPath)
 
 	m.UpdateState()
 	m.currentHistoryIndex = len(m.uiState.PromptHistory)
-	m.loadReviews()
-	m.panels.itemListPanel.model.SetItems(getItems(m.conf, m.reviewList))
-	m.panels.sourceListPanel.SetItems(getSourceItems(m.conf.Sources))
+	_, loadCmd := m.openProjectStore()
+	m.initCmd = tea.Batch(loadCmd, m.startCollecting(), m.startWatching())
 	m.onChangeListSelectedItem()
 	return m
 }
 
 func (m model) Init() tea.Cmd {
-	return m.panels.spinner.Tick
+	return tea.Batch(m.panels.spinner.Tick, m.initCmd)
 }
 
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -104,36 +182,30 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.WindowSizeMsg:
 		return m.handleWindowSize(msg)
 	case reviewMsg:
-		selectedItem := m.panels.itemListPanel.model.SelectedItem().(listItem)
-		index := findIndex(m.panels.itemListPanel.model.Items(), msg.id)
-		if index == -1 {
-			return m, nil
-		}
-		item := m.panels.itemListPanel.model.Items()[index].(listItem)
-		review := reviewInfo{
-			ID:     item.id,
-			Param:  item.param,
-			Review: msg.content,
-			State:  "finish",
+		selectedItem, _ := m.panels.itemListPanel.model.SelectedItem().(listItem)
+		cmds = append(cmds, m.storeReview(msg), m.writeNoteCmd(msg))
+		if m.reviewHistory.id == msg.id {
+			// Show the new review
+			m.reviewHistory = reviewHistoryView{id: msg.id}
 		}
-		if m.isReviewExist(msg.id) {
-			m.reviewList[m.getReviewIndex(msg.id)] = review
-		} else {
-			m.reviewList = append(m.reviewList, review)
+		delete(m.staleItems, msg.id)
+		if m.queuedReviews[msg.id] {
+			delete(m.queuedReviews, msg.id)
+			cmds = append(cmds, m.startQueuedReviews())
 		}
-		m.saveReviews()
+		cmds = append(cmds, m.recordReviewState(msg))
 		if selectedItem.id == msg.id {
-			m.panels.itemReviewPanel.SetContent(msg.content)
-			m.onChangeListSelectedItem()
+			_, cmd = m.onChangeListSelectedItem()
+			cmds = append(cmds, cmd)
 		}
-		cmd = func() tea.Msg {
+		cmds = append(cmds, func() tea.Msg {
 			return reviewStackMsg{
 				id:        msg.id,
 				operation: Remove,
 			}
-		}
+		})
 		m.UpdateState()
-		return m, cmd
+		return m, tea.Batch(cmds...)
 	case reviewStateMsg:
 		m.reviewState = msg.state
 	case reviewStackMsg:
@@ -146,7 +218,12 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.reviewStackDenominator++
 		} else {
 			m.removeReviewStack(index)
-			m.changeItemTitlePrefix(index, "☑ ")
+			prefix := reviewedPrefix
+			if i := m.getReviewIndex(msg.id); i != -1 && m.reviewList[i].State == reviewFailed {
+				// Failed reviews are listed as unreviewed, so that they are reviewed again
+				prefix = unreviewedPrefix
+			}
+			m.changeItemTitlePrefix(index, prefix)
 		}
 		m.updateReviewStackPanel()
 		if len(m.reviewStack) == 0 {
@@ -157,9 +234,33 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		cmd := m.updateReviewProgressPanel()
 		return m, cmd
 	case updateSourceListMsg:
-		m.panels.sourceListPanel.SetItems(getSourceItems(m.conf.Sources))
 		m.panels.contextListPanel.Update(msg)
-		m.panels.itemListPanel.model.SetItems(getItems(m.conf, m.reviewList))
+		// Watchers follow the enabled sources
+		cmds = append(cmds, m.startCollecting(), m.restartWatching())
+	case sourceCollectedMsg:
+		if msg.generation != m.collectGeneration {
+			return m, nil
+		}
+		return m, m.mergeCollectedItems(msg)
+	case workspacePolledMsg:
+		return m, m.handleWorkspacePolled(msg)
+	case workspaceChangedMsg:
+		return m, m.handleWorkspaceChanged(msg)
+	case list.FilterMatchesMsg:
+		// Filtering runs in the background, so the item list must receive its result regardless of focus
+		m.panels.itemListPanel.model, cmd = m.panels.itemListPanel.model.Update(msg)
+		cmds = append(cmds, cmd, m.selectItemByID(m.pendingSelectID))
+		m.pendingSelectID = ""
+		return m, tea.Batch(cmds...)
+	case previewMsg:
+		m.handlePreviewMsg(msg)
+		return m, nil
+	case followTickMsg:
+		return m, m.handleFollowTick(msg)
+	case staleCheckedMsg:
+		return m, m.handleStaleChecked(msg)
+	case notesLoadedMsg:
+		return m, m.handleNotesLoaded(msg)
 	case progress.FrameMsg:
 		progressModel, cmd := m.panels.reviewProgressPanel.Update(msg)
 		m.panels.reviewProgressPanel = progressModel.(progress.Model)
@@ -182,7 +283,8 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 		}
 	case updateFocusPanelMsg:
-		m.onChangeListSelectedItem()
+		_, cmd = m.onChangeListSelectedItem()
+		cmds = append(cmds, cmd)
 		m.setSourceDetailContent()
 	default:
 		switch m.focusState {
@@ -224,6 +326,11 @@ func (m model) View() string {
 
 func (m *model) UpdateState() (tea.Model, tea.Cmd) {
 	m.state = state.LoadState(m.stateFile)
+	if m.storage != nil {
+		if usage, err := m.storage.TotalUsage(); err == nil {
+			m.state.Usage = usage
+		}
+	}
 	m.panels.stateSummaryPanel.SetContent(m.state.ShowUsage(m.conf.ModelCost))
 	m.panels.stateDetailPanel.SetContent(m.state.ShowUsedToken())
 	return m, nil
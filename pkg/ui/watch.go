@@ -0,0 +1,264 @@
+package ui
+
+import (
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/fsnotify/fsnotify"
+	"github.com/shutils/lazyreview/pkg/config"
+	"github.com/shutils/lazyreview/pkg/ignore"
+)
+
+const (
+	defaultWatchInterval = 2 * time.Second
+	watchDebounce        = 500 * time.Millisecond
+)
+
+// workspacePolledMsg carries the fingerprint of a source's workspace taken by a poll.
+type workspacePolledMsg struct {
+	source      string
+	fingerprint string
+	generation  int
+}
+
+// workspaceChangedMsg is sent by a notify watcher when the workspace of a source changed.
+type workspaceChangedMsg struct {
+	source string
+}
+
+// gitIndexPath returns the path of the git index of the repository containing dir.
+func gitIndexPath(dir string) string {
+	root := ignore.RepoRoot(dir)
+	if root == "" {
+		return ""
+	}
+	return filepath.Join(root, ".git", "index")
+}
+
+// startWatching starts watching the workspace of every source with a watch mode.
+func (m *model) startWatching() tea.Cmd {
+	m.watchStop = make(chan struct{})
+	// A receiver is already waiting if the events channel was made by an earlier call
+	waiting := m.watchEvents != nil
+	var cmds []tea.Cmd
+	for _, source := range collectorSources(m.conf) {
+		switch source.Watch {
+		case config.WatchPoll:
+			cmds = append(cmds, m.pollWorkspaceCmd(source))
+		case config.WatchNotify:
+			if err := m.startNotifyWatcher(source); err != nil {
+				m.commandLog.add(commandResult{
+					kind:      "watcher",
+					source:    source.Name,
+					args:      []string{"watch", sourceTarget(m.conf, source)},
+					startedAt: time.Now(),
+					err:       err,
+				})
+				continue
+			}
+		}
+	}
+	if m.watchEvents != nil && !waiting {
+		cmds = append(cmds, waitForWorkspaceChange(m.watchEvents))
+	}
+	return tea.Batch(cmds...)
+}
+
+func (m *model) pollWorkspaceCmd(source config.Source) tea.Cmd {
+	interval := defaultWatchInterval
+	if source.WatchInterval > 0 {
+		interval = time.Duration(source.WatchInterval)
+	}
+	target := sourceTarget(m.conf, source)
+	generation := m.watchGeneration
+	return tea.Tick(interval, func(time.Time) tea.Msg {
+		return workspacePolledMsg{
+			source:      source.Name,
+			fingerprint: workspaceFingerprint(target),
+			generation:  generation,
+		}
+	})
+}
+
+// restartWatching stops the watchers of the previous target and watches the current one.
+func (m *model) restartWatching() tea.Cmd {
+	if m.watchStop != nil {
+		close(m.watchStop)
+	}
+	m.watchGeneration++
+	m.watchFingerprints = map[string]string{}
+	return m.startWatching()
+}
+
+// handleWorkspacePolled re-runs the collector of the polled source if its fingerprint changed
+// and schedules the next poll.
+func (m *model) handleWorkspacePolled(msg workspacePolledMsg) tea.Cmd {
+	if msg.generation != m.watchGeneration {
+		return nil
+	}
+	source, ok := m.findCollectorSource(msg.source)
+	if !ok || source.Watch != config.WatchPoll {
+		delete(m.watchFingerprints, msg.source)
+		return nil
+	}
+
+	var cmds []tea.Cmd
+	prev, polled := m.watchFingerprints[msg.source]
+	if polled && prev != msg.fingerprint {
+		cmds = append(cmds, m.collectSource(source))
+	}
+	m.watchFingerprints[msg.source] = msg.fingerprint
+	cmds = append(cmds, m.pollWorkspaceCmd(source))
+	return tea.Batch(cmds...)
+}
+
+func (m *model) handleWorkspaceChanged(msg workspaceChangedMsg) tea.Cmd {
+	cmds := []tea.Cmd{waitForWorkspaceChange(m.watchEvents)}
+	if source, ok := m.findCollectorSource(msg.source); ok {
+		cmds = append(cmds, m.collectSource(source))
+	}
+	return tea.Batch(cmds...)
+}
+
+func (m *model) findCollectorSource(name string) (config.Source, bool) {
+	for _, source := range collectorSources(m.conf) {
+		if source.Name == name {
+			return source, true
+		}
+	}
+	return config.Source{}, false
+}
+
+// workspaceFingerprint hashes the paths, sizes and modification times of the files under target
+// together with the git index, skipping ignored files.
+func workspaceFingerprint(target string) string {
+	h := fnv.New64a()
+	matcher := newIgnoreMatcher(target)
+	filepath.WalkDir(target, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if path != target && matcher.Match(path, d.IsDir()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			matcher.Load(path)
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		fmt.Fprintf(h, "%s:%d:%d\n", path, info.Size(), info.ModTime().UnixNano())
+		return nil
+	})
+	if index := gitIndexPath(target); index != "" {
+		if info, err := os.Stat(index); err == nil {
+			fmt.Fprintf(h, "index:%d:%d\n", info.Size(), info.ModTime().UnixNano())
+		}
+	}
+	return fmt.Sprintf("%x", h.Sum64())
+}
+
+// startNotifyWatcher watches the directories under the source's target and its git index.
+// Changes are debounced and reported as the source name on m.watchEvents.
+func (m *model) startNotifyWatcher(source config.Source) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	target := sourceTarget(m.conf, source)
+	matcher := newIgnoreMatcher(target)
+	if err := addWatchDirs(watcher, matcher, target); err != nil {
+		watcher.Close()
+		return err
+	}
+	index := gitIndexPath(target)
+	if index != "" {
+		// The index is replaced by a rename, so its directory is watched
+		watcher.Add(filepath.Dir(index))
+	}
+
+	if m.watchEvents == nil {
+		m.watchEvents = make(chan string)
+	}
+	events := m.watchEvents
+	stop := m.watchStop
+	go func() {
+		defer watcher.Close()
+		var debounce <-chan time.Time
+		for {
+			select {
+			case <-stop:
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if index != "" && event.Name != index && filepath.Dir(event.Name) == filepath.Dir(index) {
+					continue
+				}
+				// Removed paths can not be stat'ed, and are matched as files
+				info, err := os.Stat(event.Name)
+				isDir := err == nil && info.IsDir()
+				if event.Name != index && matcher.Match(event.Name, isDir) {
+					continue
+				}
+				if event.Has(fsnotify.Create) && isDir {
+					addWatchDirs(watcher, matcher, event.Name)
+				}
+				debounce = time.After(watchDebounce)
+			case <-debounce:
+				debounce = nil
+				select {
+				case events <- source.Name:
+				case <-stop:
+					return
+				}
+			case _, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// addWatchDirs adds root and all of its non-ignored subdirectories to watcher.
+// Nothing is added if root itself is ignored.
+func addWatchDirs(watcher *fsnotify.Watcher, matcher *ignore.Matcher, root string) error {
+	if matcher.Match(root, true) {
+		return nil
+	}
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == root {
+				return err
+			}
+			return nil
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if path != root && matcher.Match(path, true) {
+			return filepath.SkipDir
+		}
+		matcher.Load(path)
+		return watcher.Add(path)
+	})
+}
+
+func waitForWorkspaceChange(events <-chan string) tea.Cmd {
+	return func() tea.Msg {
+		return workspaceChangedMsg{source: <-events}
+	}
+}